@@ -4,7 +4,7 @@ import (
 	"context"
 	"ledger/internal/config"
 	"ledger/internal/handler"
-	"ledger/internal/models"
+	"ledger/internal/migrations"
 	"ledger/internal/repository"
 	"ledger/internal/router"
 	"ledger/internal/services"
@@ -27,13 +27,32 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	if err := db.AutoMigrate(&models.Account{}, &models.Transaction{}); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	switch dbConfig.Migrate {
+	case config.MigrateUp:
+		if err := migrator.Up(); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		slog.Info("Database migrations completed")
+	case config.MigrateCheck:
+		if err := migrator.Check(); err != nil {
+			log.Fatal("Refusing to start: ", err)
+		}
+	default:
+		log.Fatalf("Unknown DB_MIGRATE mode %q", dbConfig.Migrate)
+	}
+
+	ledgerConfig := config.GetLedgerConfig()
+	rateProvider, err := services.NewStaticRateProvider(ledgerConfig.FXRates)
+	if err != nil {
+		log.Fatal("Failed to load FX rates:", err)
 	}
-	slog.Info("Database migrations completed")
 
 	ledgerRepo := repository.NewLedgerRepository(db)
-	ledgerService := services.NewLedgerService(ledgerRepo, db)
+	ledgerService := services.NewLedgerService(ledgerRepo, ledgerConfig, rateProvider)
 	ledgerHandler := handler.NewLedgerHandler(ledgerService)
 
 	r := router.SetupRoutes(ledgerHandler)
@@ -50,7 +69,7 @@ func main() {
 
 	// Iniciar servidor em goroutine
 	go func() {
-		slog.Info("API server is running", "port", port, "workers", 10)
+		slog.Info("API server is running", "port", port, "workers", ledgerConfig.Workers)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("Failed to start server", "error", err)
 			os.Exit(1)
@@ -61,17 +80,17 @@ func main() {
 	<-shutdown
 	slog.Info("Shutting down server gracefully...")
 
-	// Shutdown dos workers
-	ledgerService.Shutdown()
-	slog.Info("Workers stopped")
-
-	// Shutdown do servidor HTTP
+	// Shutdown do servidor HTTP primeiro: as requisições em andamento
+	// esperam pelos workers, que só param depois
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("Server forced to shutdown", "error", err)
 	}
-
 	slog.Info("Server stopped")
+
+	// Shutdown dos workers
+	ledgerService.Shutdown()
+	slog.Info("Workers stopped")
 }
@@ -0,0 +1,206 @@
+// Command ledger runs administrative tasks against the ledger database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ledger/internal/config"
+	"ledger/internal/migrations"
+	"ledger/internal/repository"
+	"ledger/internal/services"
+	"ledger/internal/tenant"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: ledger <command> [flags]
+
+Commands:
+  verify    walk the postings hash chain and report the first broken link
+  apikey    manage API keys:
+              apikey create -name <name> -scopes <scope,scope,...> [-tenant <id>]
+              apikey list
+              apikey revoke <key-id>
+  migrate   manage the database schema:
+              migrate up          apply every pending migration
+              migrate down        revert the latest applied migration
+              migrate to <N>      apply or revert migrations until N is the latest applied
+              migrate status      list the migrations and when they were applied
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "verify":
+		os.Exit(runVerify(os.Args[2:]))
+	case "apikey":
+		os.Exit(runAPIKey(os.Args[2:]))
+	case "migrate":
+		os.Exit(runMigrate(os.Args[2:]))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	accountID := fs.String("account", "", "verify only this account")
+	fs.Parse(args)
+
+	db, err := config.ConnectDatabase(config.GetDatabaseConfig())
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	repo := repository.NewLedgerRepository(db).WithContext(tenant.WithBypass(context.Background()))
+	result, err := services.VerifyHashChain(repo, *accountID)
+	if err != nil {
+		log.Fatal("Verification failed:", err)
+	}
+
+	fmt.Printf("accounts checked: %d\npostings checked: %d\n", result.AccountsChecked, result.PostingsChecked)
+	if result.Valid {
+		fmt.Println("hash chain is intact")
+		return 0
+	}
+
+	b := result.FirstBreak
+	fmt.Printf("hash chain is broken\n  account:     %s\n  transaction: %s\n  sequence:    %d\n  reason:      %s\n",
+		b.AccountID, b.TransactionID, b.Sequence, b.Reason)
+	return 1
+}
+
+func runAPIKey(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	db, err := config.ConnectDatabase(config.GetDatabaseConfig())
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	repo := repository.NewLedgerRepository(db).WithContext(tenant.WithBypass(context.Background()))
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+		name := fs.String("name", "", "name of the key")
+		scopes := fs.String("scopes", "", "comma-separated scopes to grant")
+		tenantID := fs.String("tenant", tenant.Default, "tenant the key acts for")
+		fs.Parse(args[1:])
+		if *name == "" || *scopes == "" {
+			fmt.Fprintln(os.Stderr, "apikey create needs -name and -scopes")
+			return 2
+		}
+
+		scoped := repo.WithContext(tenant.WithID(context.Background(), *tenantID))
+		key, token, err := services.CreateAPIKey(scoped, *name, strings.Split(*scopes, ","))
+		if err != nil {
+			log.Fatal("Failed to create api key:", err)
+		}
+		fmt.Printf("id:     %s\ntenant: %s\nscopes: %s\nkey:    %s\n\nStore the key now; it cannot be shown again.\n",
+			key.ID, key.TenantID, strings.Join(key.Scopes, ","), token)
+		return 0
+	case "list":
+		keys, err := repo.GetAPIKeys()
+		if err != nil {
+			log.Fatal("Failed to list api keys:", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTENANT\tNAME\tPREFIX\tSCOPES\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.TenantID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+		}
+		tw.Flush()
+		return 0
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "apikey revoke needs a key id")
+			return 2
+		}
+		key, err := services.RevokeAPIKey(repo, args[1], time.Now())
+		if err != nil {
+			log.Fatal("Failed to revoke api key:", err)
+		}
+		fmt.Printf("revoked %s (%s)\n", key.ID, key.Name)
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+func runMigrate(args []string) int {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	db, err := config.ConnectDatabase(config.GetDatabaseConfig())
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+	case "down":
+		if err := migrator.Down(); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "migrate to needs a version")
+			return 2
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		if err := migrator.To(version); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+	case "status":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		log.Fatal("Failed to read migration status:", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, st := range statuses {
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, formatTime(st.AppliedAt))
+	}
+	tw.Flush()
+	return 0
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiBackend runs the commands through the HTTP API. A dry run prints the
+// first request that would write and sends nothing.
+type apiBackend struct {
+	baseURL string
+	apiKey  string
+	dryRun  bool
+	client  *http.Client
+}
+
+// apiError is a problem response from the API.
+type apiError struct {
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (%d %s)", e.Detail, e.Status, e.Code)
+}
+
+func newAPIBackend(baseURL, apiKey string, dryRun bool) *apiBackend {
+	return &apiBackend{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/v1",
+		apiKey:  apiKey,
+		dryRun:  dryRun,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (b *apiBackend) CreateAccount(ctx context.Context, req accountRequest) (*models.Account, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := b.write(ctx, http.MethodPost, "/accounts", map[string]interface{}{
+		"owner_name":      req.OwnerName,
+		"currency":        req.Currency,
+		"initial_balance": req.InitialBalance,
+		"overdraft_limit": req.Policy.OverdraftLimit,
+		"never_negative":  req.Policy.NeverNegative,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetAccount(ctx, created.ID)
+}
+
+func (b *apiBackend) GetAccount(ctx context.Context, accountID string) (*models.Account, error) {
+	var account models.Account
+	if err := b.do(ctx, http.MethodGet, "/accounts/"+url.PathEscape(accountID), nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (b *apiBackend) ListAccounts(ctx context.Context, limit, offset int) ([]models.Account, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	var page struct {
+		Accounts []models.Account `json:"accounts"`
+	}
+	if err := b.do(ctx, http.MethodGet, "/accounts?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Accounts, nil
+}
+
+func (b *apiBackend) GetBalance(ctx context.Context, accountID string) (*services.Balance, error) {
+	var balance struct {
+		AccountID        string        `json:"account_id"`
+		Currency         string        `json:"currency"`
+		LedgerBalance    models.Amount `json:"ledger_balance"`
+		AvailableBalance models.Amount `json:"available_balance"`
+	}
+	if err := b.do(ctx, http.MethodGet, "/accounts/"+url.PathEscape(accountID)+"/balance", nil, &balance); err != nil {
+		return nil, err
+	}
+	return &services.Balance{
+		AccountID:        balance.AccountID,
+		Currency:         balance.Currency,
+		LedgerBalance:    balance.LedgerBalance,
+		AvailableBalance: balance.AvailableBalance,
+	}, nil
+}
+
+func (b *apiBackend) Transfer(ctx context.Context, req services.TransferRequest) (*models.JournalEntry, error) {
+	var created struct {
+		JournalEntryID string `json:"journal_entry_id"`
+	}
+	err := b.write(ctx, http.MethodPost, "/transactions", map[string]interface{}{
+		"from_account_id": req.FromAccountID,
+		"to_account_id":   req.ToAccountID,
+		"amount":          req.Amount,
+		"description":     req.Description,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return b.getJournalEntry(ctx, created.JournalEntryID)
+}
+
+func (b *apiBackend) Reverse(ctx context.Context, transactionID string, amount models.Amount) (*models.JournalEntry, error) {
+	var body interface{}
+	if amount > 0 {
+		body = map[string]interface{}{"amount": amount}
+	}
+
+	var reversed struct {
+		JournalEntryID string `json:"journal_entry_id"`
+	}
+	if err := b.write(ctx, http.MethodPost, "/transactions/"+url.PathEscape(transactionID)+"/reverse", body, &reversed); err != nil {
+		return nil, err
+	}
+	return b.getJournalEntry(ctx, reversed.JournalEntryID)
+}
+
+func (b *apiBackend) ListTransactions(ctx context.Context, filter services.TransactionFilter, cursor string, limit int) (*services.TransactionPage, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if filter.AccountID != "" {
+		query.Set("account_id", filter.AccountID)
+	}
+	if filter.Type != "" {
+		query.Set("type", string(filter.Type))
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+
+	var page struct {
+		Transactions []models.Transaction `json:"transactions"`
+		Limit        int                  `json:"limit"`
+		NextCursor   string               `json:"next_cursor"`
+		PrevCursor   string               `json:"prev_cursor"`
+	}
+	if err := b.do(ctx, http.MethodGet, "/transactions?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &services.TransactionPage{
+		Transactions: page.Transactions,
+		Limit:        page.Limit,
+		NextCursor:   page.NextCursor,
+		PrevCursor:   page.PrevCursor,
+	}, nil
+}
+
+func (b *apiBackend) Close() {}
+
+func (b *apiBackend) getJournalEntry(ctx context.Context, entryID string) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	if err := b.do(ctx, http.MethodGet, "/journal-entries/"+url.PathEscape(entryID), nil, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// write sends a request that changes the ledger or, in a dry run, prints it.
+func (b *apiBackend) write(ctx context.Context, method, path string, body, out interface{}) error {
+	if !b.dryRun {
+		return b.do(ctx, method, path, body, out)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s%s\n", method, b.baseURL, path)
+	if body != nil {
+		payload, err := json.MarshalIndent(body, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s\n", payload)
+	}
+	return errDryRun
+}
+
+func (b *apiBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		problem := &apiError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(problem); err != nil || problem.Detail == "" {
+			problem.Detail = http.StatusText(resp.StatusCode)
+		}
+		return problem
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/config"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/services"
+	"ledger/internal/tenant"
+	"log"
+
+	"gorm.io/gorm/logger"
+)
+
+// errDryRun is returned by a dry-run command that would have succeeded.
+var errDryRun = errors.New("dry run")
+
+type accountRequest struct {
+	OwnerName      string
+	Currency       string
+	InitialBalance models.Amount
+	Policy         services.AccountPolicy
+}
+
+// backend is what the commands run against: the ledger service over the
+// database or the HTTP API.
+type backend interface {
+	CreateAccount(ctx context.Context, req accountRequest) (*models.Account, error)
+	GetAccount(ctx context.Context, accountID string) (*models.Account, error)
+	ListAccounts(ctx context.Context, limit, offset int) ([]models.Account, error)
+	GetBalance(ctx context.Context, accountID string) (*services.Balance, error)
+	Transfer(ctx context.Context, req services.TransferRequest) (*models.JournalEntry, error)
+	Reverse(ctx context.Context, transactionID string, amount models.Amount) (*models.JournalEntry, error)
+	ListTransactions(ctx context.Context, filter services.TransactionFilter, cursor string, limit int) (*services.TransactionPage, error)
+	Close()
+}
+
+// directBackend runs the ledger service in process, scoped to one tenant.
+// It leaves the background jobs to the API server.
+type directBackend struct {
+	service  *services.LedgerService
+	tenantID string
+}
+
+func newDirectBackend(tenantID string, dryRun bool) *directBackend {
+	db, err := config.ConnectDatabase(config.GetDatabaseConfig())
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	ledgerConfig := config.GetLedgerConfig()
+	ledgerConfig.Workers = 1
+	ledgerConfig.BackgroundJobs = false
+	rates, err := services.NewStaticRateProvider(ledgerConfig.FXRates)
+	if err != nil {
+		log.Fatal("Failed to load FX rates: ", err)
+	}
+
+	var store repository.LedgerStore = repository.NewLedgerRepository(db)
+	if dryRun {
+		store = dryRunStore{store}
+	}
+	return &directBackend{
+		service:  services.NewLedgerService(store, ledgerConfig, rates),
+		tenantID: tenantID,
+	}
+}
+
+func (b *directBackend) ctx(ctx context.Context) context.Context {
+	return tenant.WithID(ctx, b.tenantID)
+}
+
+func (b *directBackend) CreateAccount(ctx context.Context, req accountRequest) (*models.Account, error) {
+	return b.service.CreateAccount(b.ctx(ctx), req.OwnerName, req.Currency, req.InitialBalance, req.Policy, nil)
+}
+
+func (b *directBackend) GetAccount(ctx context.Context, accountID string) (*models.Account, error) {
+	return b.service.GetAccount(b.ctx(ctx), accountID)
+}
+
+func (b *directBackend) ListAccounts(ctx context.Context, limit, offset int) ([]models.Account, error) {
+	return b.service.ListAccounts(b.ctx(ctx), limit, offset)
+}
+
+func (b *directBackend) GetBalance(ctx context.Context, accountID string) (*services.Balance, error) {
+	return b.service.GetBalance(b.ctx(ctx), accountID)
+}
+
+func (b *directBackend) Transfer(ctx context.Context, req services.TransferRequest) (*models.JournalEntry, error) {
+	return b.service.CreateTransaction(b.ctx(ctx), req, nil)
+}
+
+func (b *directBackend) Reverse(ctx context.Context, transactionID string, amount models.Amount) (*models.JournalEntry, error) {
+	return b.service.ReverseTransaction(b.ctx(ctx), transactionID, amount, nil)
+}
+
+func (b *directBackend) ListTransactions(ctx context.Context, filter services.TransactionFilter, cursor string, limit int) (*services.TransactionPage, error) {
+	return b.service.ListTransactions(b.ctx(ctx), filter, cursor, limit)
+}
+
+func (b *directBackend) Close() {
+	b.service.Shutdown()
+}
+
+// dryRunStore rolls back every transaction once it has run to the end, so
+// that a command goes through all of the service's checks against the
+// database without writing anything.
+type dryRunStore struct {
+	repository.LedgerStore
+}
+
+func (s dryRunStore) WithContext(ctx context.Context) repository.LedgerStore {
+	return dryRunStore{s.LedgerStore.WithContext(ctx)}
+}
+
+func (s dryRunStore) Transaction(fn func(tx repository.Tx) error) error {
+	return s.LedgerStore.Transaction(func(tx repository.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+}
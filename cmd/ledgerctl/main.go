@@ -0,0 +1,345 @@
+// Command ledgerctl is the operator's command line for day-to-day work on
+// the ledger. It talks to the API when given its URL and otherwise runs the
+// ledger service directly against the database.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"ledger/internal/tenant"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: ledgerctl [global flags] <command> [flags]
+
+Commands:
+  account create -owner <name> [-currency USD] [-initial-balance <amount>]
+                 [-overdraft-limit <amount>] [-never-negative] [-dry-run]
+  account show <account-id>
+  account list [-limit 50] [-offset 0]
+  balance <account-id>
+  transfer -from <account-id> -to <account-id> -amount <amount>
+           [-description <text>] [-dry-run]
+  reverse <transaction-id> [-amount <amount>] [-dry-run]
+  transaction list [-account <account-id>] [-type DEBIT|CREDIT]
+                   [-from <RFC 3339>] [-to <RFC 3339>] [-limit 50] [-cursor <cursor>]
+
+Global flags:
+  -api <url>        API to talk to, e.g. http://localhost:8080 (LEDGER_API_URL);
+                    without it ledgerctl uses the database (DB_* settings)
+  -api-key <key>    API key to authenticate with (LEDGER_API_KEY)
+  -tenant <id>      tenant to act for when using the database (default "default")
+  -o <format>       output format: table, json or csv (default table)
+
+With -dry-run, a command against the database runs every check and is then
+rolled back; against the API it prints the request it would send.
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ledgerctl: ")
+
+	global := flag.NewFlagSet("ledgerctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	apiURL := global.String("api", os.Getenv("LEDGER_API_URL"), "")
+	apiKey := global.String("api-key", os.Getenv("LEDGER_API_KEY"), "")
+	tenantID := global.String("tenant", tenant.Default, "")
+	format := global.String("o", formatTable, "")
+	global.Parse(os.Args[1:])
+
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	args := global.Args()
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cli := &cli{
+		out: out,
+		open: func(dryRun bool) backend {
+			if *apiURL != "" {
+				return newAPIBackend(*apiURL, *apiKey, dryRun)
+			}
+			return newDirectBackend(*tenantID, dryRun)
+		},
+	}
+	os.Exit(cli.run(args))
+}
+
+type cli struct {
+	out  *printer
+	open func(dryRun bool) backend
+}
+
+func (c *cli) run(args []string) int {
+	switch args[0] {
+	case "account":
+		if len(args) < 2 {
+			break
+		}
+		switch args[1] {
+		case "create":
+			return c.createAccount(args[2:])
+		case "show":
+			return c.showAccount(args[2:])
+		case "list":
+			return c.listAccounts(args[2:])
+		}
+	case "balance":
+		return c.balance(args[1:])
+	case "transfer":
+		return c.transfer(args[1:])
+	case "reverse":
+		return c.reverse(args[1:])
+	case "transaction":
+		if len(args) >= 2 && args[1] == "list" {
+			return c.listTransactions(args[2:])
+		}
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+	return 2
+}
+
+func (c *cli) createAccount(args []string) int {
+	fs := flag.NewFlagSet("account create", flag.ExitOnError)
+	owner := fs.String("owner", "", "owner of the account")
+	currency := fs.String("currency", models.DefaultCurrency, "ISO 4217 currency of the account")
+	initialBalance := amountFlag(fs, "initial-balance", "balance to open the account with")
+	overdraftLimit := amountFlag(fs, "overdraft-limit", "how far the account may go negative")
+	neverNegative := fs.Bool("never-negative", false, "never let the account go negative")
+	dryRun := fs.Bool("dry-run", false, "check the command without writing anything")
+	fs.Parse(args)
+	if *owner == "" {
+		fmt.Fprintln(os.Stderr, "account create needs -owner")
+		return 2
+	}
+
+	b := c.open(*dryRun)
+	defer b.Close()
+
+	account, err := b.CreateAccount(context.Background(), accountRequest{
+		OwnerName:      *owner,
+		Currency:       strings.ToUpper(*currency),
+		InitialBalance: *initialBalance,
+		Policy: services.AccountPolicy{
+			OverdraftLimit: *overdraftLimit,
+			NeverNegative:  *neverNegative,
+		},
+	})
+	if done, code := c.finish(err, "account create"); done {
+		return code
+	}
+	return c.print(c.out.accounts(account, []models.Account{*account}))
+}
+
+func (c *cli) showAccount(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "account show needs an account id")
+		return 2
+	}
+
+	b := c.open(false)
+	defer b.Close()
+
+	account, err := b.GetAccount(context.Background(), args[0])
+	if done, code := c.finish(err, "account show"); done {
+		return code
+	}
+	return c.print(c.out.accounts(account, []models.Account{*account}))
+}
+
+func (c *cli) listAccounts(args []string) int {
+	fs := flag.NewFlagSet("account list", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "accounts per page")
+	offset := fs.Int("offset", 0, "accounts to skip")
+	fs.Parse(args)
+
+	b := c.open(false)
+	defer b.Close()
+
+	accounts, err := b.ListAccounts(context.Background(), *limit, *offset)
+	if done, code := c.finish(err, "account list"); done {
+		return code
+	}
+	return c.print(c.out.accounts(accounts, accounts))
+}
+
+func (c *cli) balance(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "balance needs an account id")
+		return 2
+	}
+
+	b := c.open(false)
+	defer b.Close()
+
+	balance, err := b.GetBalance(context.Background(), args[0])
+	if done, code := c.finish(err, "balance"); done {
+		return code
+	}
+	return c.print(c.out.balance(balance))
+}
+
+func (c *cli) transfer(args []string) int {
+	fs := flag.NewFlagSet("transfer", flag.ExitOnError)
+	from := fs.String("from", "", "account to debit")
+	to := fs.String("to", "", "account to credit")
+	amount := amountFlag(fs, "amount", "amount to transfer")
+	description := fs.String("description", "", "description of the transfer")
+	dryRun := fs.Bool("dry-run", false, "check the command without writing anything")
+	fs.Parse(args)
+	if *from == "" || *to == "" || *amount <= 0 {
+		fmt.Fprintln(os.Stderr, "transfer needs -from, -to and a positive -amount")
+		return 2
+	}
+
+	b := c.open(*dryRun)
+	defer b.Close()
+
+	entry, err := b.Transfer(context.Background(), services.TransferRequest{
+		FromAccountID: *from,
+		ToAccountID:   *to,
+		Amount:        *amount,
+		Description:   *description,
+	})
+	if done, code := c.finish(err, "transfer"); done {
+		return code
+	}
+	return c.print(c.out.journalEntry(entry))
+}
+
+func (c *cli) reverse(args []string) int {
+	transactionID, args := leadingArg(args)
+	fs := flag.NewFlagSet("reverse", flag.ExitOnError)
+	amount := amountFlag(fs, "amount", "amount to reverse; the whole entry when not set")
+	dryRun := fs.Bool("dry-run", false, "check the command without writing anything")
+	fs.Parse(args)
+	if transactionID == "" {
+		transactionID = fs.Arg(0)
+	}
+	if transactionID == "" {
+		fmt.Fprintln(os.Stderr, "reverse needs a transaction id")
+		return 2
+	}
+
+	b := c.open(*dryRun)
+	defer b.Close()
+
+	entry, err := b.Reverse(context.Background(), transactionID, *amount)
+	if done, code := c.finish(err, "reverse"); done {
+		return code
+	}
+	return c.print(c.out.journalEntry(entry))
+}
+
+func (c *cli) listTransactions(args []string) int {
+	fs := flag.NewFlagSet("transaction list", flag.ExitOnError)
+	accountID := fs.String("account", "", "only postings to this account")
+	postingType := fs.String("type", "", "only DEBIT or CREDIT postings")
+	from := timeFlag(fs, "from", "only postings made at or after this time")
+	to := timeFlag(fs, "to", "only postings made before this time")
+	limit := fs.Int("limit", 50, "postings per page, at most 100")
+	cursor := fs.String("cursor", "", "cursor of the page to show, from a previous listing")
+	fs.Parse(args)
+
+	filter := services.TransactionFilter{
+		AccountID: *accountID,
+		Type:      models.TransactionType(strings.ToUpper(*postingType)),
+		From:      *from,
+		To:        *to,
+	}
+	if filter.Type != "" && filter.Type != models.TransactionTypeDebit && filter.Type != models.TransactionTypeCredit {
+		fmt.Fprintln(os.Stderr, "-type must be DEBIT or CREDIT")
+		return 2
+	}
+
+	b := c.open(false)
+	defer b.Close()
+
+	page, err := b.ListTransactions(context.Background(), filter, *cursor, *limit)
+	if done, code := c.finish(err, "transaction list"); done {
+		return code
+	}
+	if code := c.print(c.out.transactions(page)); code != 0 {
+		return code
+	}
+	if c.out.format != formatJSON {
+		if page.NextCursor != "" {
+			fmt.Fprintln(os.Stderr, "next page: -cursor", page.NextCursor)
+		}
+		if page.PrevCursor != "" {
+			fmt.Fprintln(os.Stderr, "previous page: -cursor", page.PrevCursor)
+		}
+	}
+	return 0
+}
+
+// finish reports a failed or dry-run command. It returns done when there is
+// no result to print, with the exit code to end on.
+func (c *cli) finish(err error, command string) (done bool, code int) {
+	switch {
+	case err == nil:
+		return false, 0
+	case errors.Is(err, errDryRun):
+		fmt.Fprintf(os.Stderr, "dry run: %s would succeed; nothing was written\n", command)
+		return true, 0
+	default:
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", command, err)
+		return true, 1
+	}
+}
+
+func (c *cli) print(err error) int {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write output:", err)
+		return 1
+	}
+	return 0
+}
+
+// leadingArg takes a positional argument written before the flags, as in
+// "reverse <id> -amount 5", which the flag package would stop at.
+func leadingArg(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+func amountFlag(fs *flag.FlagSet, name, usage string) *models.Amount {
+	var amount models.Amount
+	fs.Func(name, usage, func(s string) error {
+		parsed, err := models.ParseAmount(s)
+		if err != nil {
+			return err
+		}
+		amount = parsed
+		return nil
+	})
+	return &amount
+}
+
+func timeFlag(fs *flag.FlagSet, name, usage string) *time.Time {
+	var t time.Time
+	fs.Func(name, usage, func(s string) error {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("must be an RFC 3339 timestamp")
+		}
+		t = parsed
+		return nil
+	})
+	return &t
+}
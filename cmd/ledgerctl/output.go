@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// printer writes command results as a table for people, or as JSON or CSV
+// for scripts.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q: use table, json or csv", format)
+	}
+}
+
+// accounts prints v, the account or accounts as returned, with one row per
+// account.
+func (p *printer) accounts(v interface{}, accounts []models.Account) error {
+	rows := make([][]string, len(accounts))
+	for i, a := range accounts {
+		rows[i] = []string{
+			a.ID, a.OwnerName, a.Currency, string(a.Status),
+			a.Balance.String(), a.HeldBalance.String(), a.OverdraftLimit.String(),
+			strconv.FormatBool(a.NeverNegative), formatTime(a.CreatedAt),
+		}
+	}
+	return p.render(v, []string{
+		"id", "owner", "currency", "status",
+		"balance", "held", "overdraft_limit",
+		"never_negative", "created_at",
+	}, rows)
+}
+
+func (p *printer) balance(b *services.Balance) error {
+	v := struct {
+		AccountID        string        `json:"account_id"`
+		Currency         string        `json:"currency"`
+		LedgerBalance    models.Amount `json:"ledger_balance"`
+		AvailableBalance models.Amount `json:"available_balance"`
+	}{b.AccountID, b.Currency, b.LedgerBalance, b.AvailableBalance}
+
+	return p.render(v, []string{"account_id", "currency", "ledger_balance", "available_balance"}, [][]string{{
+		b.AccountID, b.Currency, b.LedgerBalance.String(), b.AvailableBalance.String(),
+	}})
+}
+
+// journalEntry prints an entry with one row per posting.
+func (p *printer) journalEntry(entry *models.JournalEntry) error {
+	rows := make([][]string, len(entry.Postings))
+	for i, t := range entry.Postings {
+		rows[i] = []string{
+			entry.ID, string(entry.Type), t.ID, t.AccountID,
+			string(t.Type), t.Amount.String(), t.Currency, formatTime(entry.CreatedAt),
+		}
+	}
+	return p.render(entry, []string{
+		"journal_entry_id", "entry_type", "transaction_id", "account_id",
+		"type", "amount", "currency", "created_at",
+	}, rows)
+}
+
+// transactions prints a page of postings. In JSON the page carries its
+// cursors; the other formats leave them to the caller.
+func (p *printer) transactions(page *services.TransactionPage) error {
+	rows := make([][]string, len(page.Transactions))
+	for i, t := range page.Transactions {
+		rows[i] = []string{
+			t.ID, t.JournalEntryID, t.AccountID, string(t.Type),
+			t.Amount.String(), t.Currency, t.Description, formatTime(t.CreatedAt),
+		}
+	}
+	v := struct {
+		Transactions []models.Transaction `json:"transactions"`
+		NextCursor   string               `json:"next_cursor,omitempty"`
+		PrevCursor   string               `json:"prev_cursor,omitempty"`
+	}{page.Transactions, page.NextCursor, page.PrevCursor}
+
+	return p.render(v, []string{
+		"id", "journal_entry_id", "account_id", "type",
+		"amount", "currency", "description", "created_at",
+	}, rows)
+}
+
+// render writes v as JSON, or header and rows as CSV or an aligned table.
+func (p *printer) render(v interface{}, header []string, rows [][]string) error {
+	switch p.format {
+	case formatJSON:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatCSV:
+		w := csv.NewWriter(p.w)
+		w.Write(header)
+		w.WriteAll(rows)
+		return w.Error()
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	titles := make([]string, len(header))
+	for i, h := range header {
+		titles[i] = strings.ToUpper(strings.ReplaceAll(h, "_", " "))
+	}
+	fmt.Fprintln(tw, strings.Join(titles, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
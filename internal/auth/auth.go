@@ -0,0 +1,68 @@
+// Package auth carries the authenticated caller of a request and the scopes
+// API keys can be granted.
+package auth
+
+import "context"
+
+const (
+	ScopeAccountsRead     = "accounts:read"
+	ScopeAccountsWrite    = "accounts:write"
+	ScopeTransfersRead    = "transfers:read"
+	ScopeTransfersWrite   = "transfers:write"
+	ScopeTransfersReverse = "transfers:reverse"
+	// ScopeAdmin manages API keys and webhooks and grants every other
+	// scope.
+	ScopeAdmin = "admin"
+)
+
+var scopes = map[string]bool{
+	ScopeAccountsRead:     true,
+	ScopeAccountsWrite:    true,
+	ScopeTransfersRead:    true,
+	ScopeTransfersWrite:   true,
+	ScopeTransfersReverse: true,
+	ScopeAdmin:            true,
+}
+
+func ValidScope(scope string) bool {
+	return scopes[scope]
+}
+
+// Principal is the API key a request was authenticated with and the tenant
+// the key belongs to.
+type Principal struct {
+	KeyID    string
+	TenantID string
+	Scopes   []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the request's principal, or nil for work that no API
+// key started, such as the scheduler's.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// KeyID returns the ID of the caller's API key, or nil when there is none.
+func KeyID(ctx context.Context) *string {
+	if p := FromContext(ctx); p != nil {
+		id := p.KeyID
+		return &id
+	}
+	return nil
+}
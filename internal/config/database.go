@@ -4,33 +4,64 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// How the API server treats pending migrations at startup: MigrateUp applies
+// them, MigrateCheck refuses to start until they have been applied with
+// `ledger migrate up`.
+const (
+	MigrateUp    = "up"
+	MigrateCheck = "check"
+)
+
+// sqliteOptions make concurrent writers wait for each other instead of
+// failing at once, and open every read-write transaction with BEGIN
+// IMMEDIATE, which takes the database's write lock up front: SQLite has no
+// row locks, so that lock is what the repository's FOR UPDATE reads rely
+// on.
+const sqliteOptions = "_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate"
+
 type DatabaseConfig struct {
+	Driver   string
+	Path     string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+	Migrate  string
 }
 
 func GetDatabaseConfig() *DatabaseConfig {
 	return &DatabaseConfig{
+		Driver:   getEnv("DB_DRIVER", DriverPostgres),
+		Path:     getEnv("DB_PATH", "ledger.db"),
 		Host:     getEnv("DB_HOST", "localhost"),
 		Port:     getEnv("DB_PORT", "5432"),
 		User:     getEnv("DB_USER", "postgres"),
 		Password: getEnv("DB_PASSWORD", "postgres"),
 		DBName:   getEnv("DB_NAME", "ledger"),
 		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+		Migrate:  getEnv("DB_MIGRATE", MigrateUp),
 	}
 }
 
 func (c *DatabaseConfig) GetDSN() string {
+	if c.Driver == DriverSQLite {
+		return fmt.Sprintf("file:%s?%s", c.Path, sqliteOptions)
+	}
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
@@ -38,10 +69,22 @@ func (c *DatabaseConfig) GetDSN() string {
 }
 
 func ConnectDatabase(config *DatabaseConfig) (*gorm.DB, error) {
-	dsn := config.GetDSN()
+	var dialector gorm.Dialector
+	switch config.Driver {
+	case DriverPostgres:
+		dialector = postgres.Open(config.GetDSN())
+	case DriverSQLite:
+		dialector = sqlite.Open(config.GetDSN())
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	// The timestamps GORM fills in are UTC, like every other time the
+	// repository writes: SQLite stores times as text and compares them as
+	// such, which only orders them correctly when they share a zone.
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:  logger.Default.LogMode(logger.Info),
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 
 	if err != nil {
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"ledger/internal/models"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LedgerConfig struct {
+	Workers   int
+	QueueSize int
+	// BackgroundJobs runs the idempotency key purge, hold expiry, transfer
+	// recovery, scheduler and webhook dispatcher next to the workers.
+	// Short-lived processes such as ledgerctl leave them to the API server.
+	BackgroundJobs bool
+
+	IdempotencyTTL time.Duration
+	FXQuoteTTL     time.Duration
+	FXSpreadBps    int
+	FXRates        map[string]string
+
+	HoldDefaultTTL    time.Duration
+	HoldSweepInterval time.Duration
+
+	TransferTimeout      time.Duration
+	TransferPollInterval time.Duration
+
+	BatchMaxItems int
+	// BatchMaxTotal caps the sum of a batch's amounts in each currency. Zero
+	// means no cap.
+	BatchMaxTotal models.Amount
+
+	SchedulerInterval time.Duration
+	// ScheduleCatchUp is the catch-up policy of schedules created without
+	// one. ScheduleMissedAfter is how late a run may be before the skip
+	// policy treats it as missed.
+	ScheduleCatchUp     models.CatchUpPolicy
+	ScheduleMissedAfter time.Duration
+
+	WebhookDispatchInterval time.Duration
+	WebhookTimeout          time.Duration
+	// A failed delivery is retried after WebhookBackoffBase, doubling on
+	// every attempt up to WebhookBackoffMax, until it has been attempted
+	// WebhookMaxAttempts times and is dead-lettered.
+	WebhookMaxAttempts int
+	WebhookBackoffBase time.Duration
+	WebhookBackoffMax  time.Duration
+}
+
+func GetLedgerConfig() *LedgerConfig {
+	return &LedgerConfig{
+		Workers:   getIntEnv("LEDGER_WORKERS", 10),
+		QueueSize: getIntEnv("LEDGER_QUEUE_SIZE", 100),
+
+		BackgroundJobs: getBoolEnv("LEDGER_BACKGROUND_JOBS", true),
+
+		IdempotencyTTL: getDurationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
+		FXQuoteTTL:     getDurationEnv("FX_QUOTE_TTL", 30*time.Second),
+		FXSpreadBps:    getIntEnv("FX_SPREAD_BPS", 0),
+		FXRates:        getRatesEnv("FX_RATES"),
+
+		HoldDefaultTTL:    getDurationEnv("HOLD_DEFAULT_TTL", 7*24*time.Hour),
+		HoldSweepInterval: getDurationEnv("HOLD_SWEEP_INTERVAL", 30*time.Second),
+
+		TransferTimeout:      getDurationEnv("TRANSFER_TIMEOUT", 10*time.Second),
+		TransferPollInterval: getDurationEnv("TRANSFER_POLL_INTERVAL", 5*time.Second),
+
+		BatchMaxItems: getIntEnv("BATCH_MAX_ITEMS", 500),
+		BatchMaxTotal: getAmountEnv("BATCH_MAX_TOTAL", 0),
+
+		SchedulerInterval:   getDurationEnv("SCHEDULER_INTERVAL", 10*time.Second),
+		ScheduleCatchUp:     getCatchUpEnv("SCHEDULE_CATCH_UP", models.CatchUpLatest),
+		ScheduleMissedAfter: getDurationEnv("SCHEDULE_MISSED_AFTER", 5*time.Minute),
+
+		WebhookDispatchInterval: getDurationEnv("WEBHOOK_DISPATCH_INTERVAL", 2*time.Second),
+		WebhookTimeout:          getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:      getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase:      getDurationEnv("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:       getDurationEnv("WEBHOOK_BACKOFF_MAX", time.Hour),
+	}
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getAmountEnv(key string, defaultValue models.Amount) models.Amount {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	amount, err := models.ParseAmount(value)
+	if err != nil || amount < 0 {
+		log.Printf("Invalid amount for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return amount
+}
+
+func getCatchUpEnv(key string, defaultValue models.CatchUpPolicy) models.CatchUpPolicy {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	policy := models.CatchUpPolicy(value)
+	if !policy.Valid() {
+		log.Printf("Invalid catch-up policy for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return policy
+}
+
+// getRatesEnv reads a rate table such as "EUR/BRL=5.43,USD/BRL=5.01".
+func getRatesEnv(key string) map[string]string {
+	rates := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		currencies, rate, ok := strings.Cut(pair, "=")
+		if !ok {
+			log.Printf("Invalid rate %q in %s, ignoring", pair, key)
+			continue
+		}
+		rates[strings.ToUpper(strings.TrimSpace(currencies))] = strings.TrimSpace(rate)
+	}
+	return rates
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
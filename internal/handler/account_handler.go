@@ -1,55 +1,305 @@
 package handler
 
 import (
+	"ledger/internal/models"
+	"ledger/internal/services"
 	"ledger/internal/utils"
 	"net/http"
-
-	"github.com/go-chi/chi/v5"
+	"strconv"
+	"time"
 )
 
 type CreateAccountRequest struct {
-	OwnerName      string  `json:"owner_name" validate:"required,min=3,max=100"`
-	InitialBalance float64 `json:"initial_balance" validate:"required,gte=0"`
+	OwnerName      string        `json:"owner_name" validate:"required,min=3,max=100"`
+	Currency       string        `json:"currency" validate:"omitempty,iso4217"`
+	InitialBalance models.Amount `json:"initial_balance" validate:"gte=0"`
+	OverdraftLimit models.Amount `json:"overdraft_limit" validate:"gte=0"`
+	NeverNegative  bool          `json:"never_negative"`
+}
+
+type ChangeAccountStatusRequest struct {
+	Status models.AccountStatus `json:"status" validate:"required,oneof=active frozen debit_blocked credit_blocked closed"`
+	Reason string               `json:"reason" validate:"required,max=255"`
+}
+
+type UpdateAccountRequest struct {
+	OverdraftLimit *models.Amount `json:"overdraft_limit" validate:"omitempty,gte=0"`
+	NeverNegative  *bool          `json:"never_negative"`
+	Reason         string         `json:"reason" validate:"required,max=255"`
 }
 
 func (h *LedgerHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	idem, done := h.idempotency(w, r)
+	if done {
+		return
+	}
+
 	data := &CreateAccountRequest{}
 
 	if utils.DecodeAndValidate(w, r, h.Validate, data) {
 		return
 	}
 
-	account, err := h.LedgerService.CreateAccount(data.OwnerName, data.InitialBalance)
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return http.StatusCreated, accountCreatedResponse(result.(*models.Account))
+		}
+	}
+
+	account, err := h.LedgerService.CreateAccount(r.Context(), data.OwnerName, data.Currency, data.InitialBalance, services.AccountPolicy{
+		OverdraftLimit: data.OverdraftLimit,
+		NeverNegative:  data.NeverNegative,
+	}, idem)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusInternalServerError, err.Error())
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
 		return
 	}
 
-	utils.SuccessResponse(w, r, http.StatusCreated, map[string]interface{}{
+	utils.SuccessResponse(w, r, http.StatusCreated, accountCreatedResponse(account))
+}
+
+func accountCreatedResponse(account *models.Account) map[string]interface{} {
+	return map[string]interface{}{
 		"message":         "account created",
 		"id":              account.ID,
 		"owner_name":      account.OwnerName,
+		"currency":        account.Currency,
 		"initial_balance": account.Balance,
+		"overdraft_limit": account.OverdraftLimit,
+		"never_negative":  account.NeverNegative,
 		"created_at":      account.CreatedAt,
+	}
+}
+
+func (h *LedgerHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	account, err := h.LedgerService.GetAccount(r.Context(), accountID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, account)
+}
+
+func (h *LedgerHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	offset := 0
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, services.MaxAccountPageSize)
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	accounts, err := h.LedgerService.ListAccounts(r.Context(), limit, offset)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"accounts": accounts,
+		"limit":    limit,
+		"offset":   offset,
 	})
 }
 
 func (h *LedgerHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
-	accountID := chi.URLParam(r, "accountID")
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	if asOfStr := r.URL.Query().Get("as_of"); asOfStr != "" {
+		asOf, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			badRequest(w, r, "as_of must be an RFC 3339 timestamp")
+			return
+		}
+
+		balance, err := h.LedgerService.GetBalanceAsOf(r.Context(), accountID, asOf)
+		if err != nil {
+			errorResponse(w, r, err)
+			return
+		}
+
+		utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+			"account_id":     balance.AccountID,
+			"currency":       balance.Currency,
+			"balance":        balance.LedgerBalance,
+			"ledger_balance": balance.LedgerBalance,
+			"as_of":          asOf,
+		})
+		return
+	}
+
+	balance, err := h.LedgerService.GetBalance(r.Context(), accountID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"account_id":        balance.AccountID,
+		"currency":          balance.Currency,
+		"balance":           balance.LedgerBalance,
+		"ledger_balance":    balance.LedgerBalance,
+		"available_balance": balance.AvailableBalance,
+	})
+}
+
+func (h *LedgerHandler) GetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	to := time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := parseDay(toStr)
+		if err != nil {
+			badRequest(w, r, "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := parseDay(fromStr)
+		if err != nil {
+			badRequest(w, r, "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return
+		}
+		from = parsed
+	}
+
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = services.BalanceIntervalDay
+	}
+
+	buckets, err := h.LedgerService.GetBalanceHistory(r.Context(), accountID, from, to, interval)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	history := make([]map[string]interface{}, len(buckets))
+	for i, b := range buckets {
+		history[i] = map[string]interface{}{
+			"start":           b.Start.Format(time.DateOnly),
+			"end":             b.End.Format(time.DateOnly),
+			"opening_balance": b.OpeningBalance,
+			"closing_balance": b.ClosingBalance,
+			"total_debits":    b.TotalDebits,
+			"total_credits":   b.TotalCredits,
+		}
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"account_id": accountID,
+		"interval":   interval,
+		"history":    history,
+	})
+}
+
+// parseDay accepts a plain date or a full RFC 3339 timestamp.
+func parseDay(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (h *LedgerHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	data := &UpdateAccountRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	account, err := h.LedgerService.UpdateAccountPolicy(r.Context(), accountID, services.AccountPolicyUpdate{
+		OverdraftLimit: data.OverdraftLimit,
+		NeverNegative:  data.NeverNegative,
+	}, data.Reason)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, account)
+}
+
+func (h *LedgerHandler) GetAccountAuditLog(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	logs, err := h.LedgerService.GetAccountAuditLog(r.Context(), accountID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"account_id": accountID,
+		"entries":    logs,
+	})
+}
+
+func (h *LedgerHandler) ChangeAccountStatus(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	data := &ChangeAccountStatusRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	account, err := h.LedgerService.ChangeAccountStatus(r.Context(), accountID, data.Status, data.Reason)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, account)
+}
 
-	if accountID == "" {
-		utils.ErrorResponse(w, r, http.StatusBadRequest, "account_id is required")
+func (h *LedgerHandler) GetAccountStatusHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
 		return
 	}
 
-	balance, err := h.LedgerService.GetBalance(accountID)
+	changes, err := h.LedgerService.GetAccountStatusHistory(r.Context(), accountID)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusNotFound, err.Error())
+		errorResponse(w, r, err)
 		return
 	}
 
 	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
 		"account_id": accountID,
-		"balance":    balance,
+		"changes":    changes,
 	})
 }
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"ledger/internal/utils"
+	"net/http"
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=accounts:read accounts:write transfers:read transfers:write transfers:reverse admin"`
+}
+
+func (h *LedgerHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := &CreateAPIKeyRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	key, token, err := h.LedgerService.CreateAPIKey(r.Context(), data.Name, data.Scopes)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	// The token is only ever returned here.
+	utils.SuccessResponse(w, r, http.StatusCreated, map[string]interface{}{
+		"id":         key.ID,
+		"name":       key.Name,
+		"prefix":     key.Prefix,
+		"scopes":     key.Scopes,
+		"key":        token,
+		"created_at": key.CreatedAt,
+	})
+}
+
+func (h *LedgerHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.LedgerService.ListAPIKeys(r.Context())
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"api_keys": keys,
+	})
+}
+
+func (h *LedgerHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := h.urlID(w, r, "keyID", "key_id")
+	if !ok {
+		return
+	}
+
+	key, err := h.LedgerService.RevokeAPIKey(r.Context(), keyID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, key)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"ledger/internal/utils"
+	"net/http"
+)
+
+func (h *LedgerHandler) VerifyAuditChain(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+	if accountID != "" && h.Validate.Var(accountID, "uuid") != nil {
+		badRequest(w, r, "account_id must be a uuid")
+		return
+	}
+
+	result, err := h.LedgerService.VerifyHashChain(r.Context(), accountID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"valid":            result.Valid,
+		"accounts_checked": result.AccountsChecked,
+		"postings_checked": result.PostingsChecked,
+	}
+	if b := result.FirstBreak; b != nil {
+		response["first_broken_link"] = map[string]interface{}{
+			"account_id":     b.AccountID,
+			"transaction_id": b.TransactionID,
+			"sequence":       b.Sequence,
+			"reason":         b.Reason,
+		}
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/services"
+	"ledger/internal/tenant"
+	"ledger/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// Authenticate requires a valid API key in the Authorization header and
+// puts its principal and tenant on the request context.
+func (h *LedgerHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ledger"`)
+			utils.ProblemResponse(w, r, http.StatusUnauthorized, "missing_api_key", "missing api key")
+			return
+		}
+
+		principal, err := h.LedgerService.AuthenticateAPIKey(token)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="ledger", error="invalid_token"`)
+			}
+			errorResponse(w, r, err)
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = tenant.WithID(ctx, principal.TenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope rejects requests whose API key was not granted scope.
+func (h *LedgerHandler) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			if principal == nil || !principal.HasScope(scope) {
+				utils.ProblemResponse(w, r, http.StatusForbidden, "insufficient_scope", "api key lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
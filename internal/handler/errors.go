@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"ledger/internal/utils"
+	"log/slog"
+	"net/http"
+)
+
+var kindStatus = map[services.ErrorKind]int{
+	services.KindInvalid:         http.StatusBadRequest,
+	services.KindNotFound:        http.StatusNotFound,
+	services.KindConflict:        http.StatusConflict,
+	services.KindRejected:        http.StatusUnprocessableEntity,
+	services.KindUnauthenticated: http.StatusUnauthorized,
+	services.KindUnavailable:     http.StatusServiceUnavailable,
+}
+
+// modelErrors maps the validation errors of the models package, which has
+// no error codes of its own.
+var modelErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{models.ErrUnsupportedCurrency, http.StatusUnprocessableEntity, "unsupported_currency"},
+	{models.ErrCurrencyPrecision, http.StatusBadRequest, "invalid_amount_precision"},
+	{models.ErrAmountPrecision, http.StatusBadRequest, "invalid_amount_precision"},
+	{models.ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{models.ErrAmountOverflow, http.StatusBadRequest, "invalid_amount"},
+}
+
+// errorResponse writes err as a problem response. Errors without a code are
+// logged and reported as internal errors without their detail.
+func errorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var ledgerErr *services.LedgerError
+	if errors.As(err, &ledgerErr) {
+		status, ok := kindStatus[ledgerErr.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		utils.ProblemResponse(w, r, status, ledgerErr.Code, err.Error())
+		return
+	}
+
+	for _, modelErr := range modelErrors {
+		if errors.Is(err, modelErr.err) {
+			utils.ProblemResponse(w, r, modelErr.status, modelErr.code, err.Error())
+			return
+		}
+	}
+
+	slog.Error("request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	utils.ProblemResponse(w, r, http.StatusInternalServerError, "internal_error", "internal server error")
+}
+
+// badRequest rejects a request whose parameters could not be parsed.
+func badRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	utils.ProblemResponse(w, r, http.StatusBadRequest, "invalid_request", detail)
+}
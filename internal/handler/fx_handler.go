@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"ledger/internal/models"
+	"ledger/internal/utils"
+	"net/http"
+)
+
+type CreateFXQuoteRequest struct {
+	FromCurrency string        `json:"from_currency" validate:"required,iso4217"`
+	ToCurrency   string        `json:"to_currency" validate:"required,iso4217,nefield=FromCurrency"`
+	Amount       models.Amount `json:"amount" validate:"gte=0"`
+}
+
+func (h *LedgerHandler) CreateFXQuote(w http.ResponseWriter, r *http.Request) {
+	data := &CreateFXQuoteRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	quote, err := h.LedgerService.CreateFXQuote(r.Context(), data.FromCurrency, data.ToCurrency)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"id":            quote.ID,
+		"from_currency": quote.FromCurrency,
+		"to_currency":   quote.ToCurrency,
+		"rate":          quote.Rate,
+		"expires_at":    quote.ExpiresAt,
+	}
+
+	if data.Amount > 0 {
+		converted, err := h.LedgerService.ConvertWithQuote(quote, data.Amount)
+		if err != nil {
+			errorResponse(w, r, err)
+			return
+		}
+		response["amount"] = data.Amount
+		response["converted_amount"] = converted
+	}
+
+	utils.SuccessResponse(w, r, http.StatusCreated, response)
+}
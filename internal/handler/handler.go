@@ -2,7 +2,9 @@ package handler
 
 import (
 	"ledger/internal/services"
+	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -17,3 +19,15 @@ func NewLedgerHandler(LedgerService *services.LedgerService) *LedgerHandler {
 		LedgerService: LedgerService,
 	}
 }
+
+// urlID returns the URL parameter param, the ID of the record the request
+// names. IDs are UUIDs, which Postgres refuses to compare with anything
+// else, so a malformed one is rejected as a bad request.
+func (h *LedgerHandler) urlID(w http.ResponseWriter, r *http.Request, param, name string) (string, bool) {
+	id := chi.URLParam(r, param)
+	if err := h.Validate.Var(id, "required,uuid"); err != nil {
+		badRequest(w, r, name+" must be a uuid")
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"ledger/internal/config"
+	"ledger/internal/handler"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"ledger/internal/router"
+	"ledger/internal/services"
+	"ledger/internal/tenant"
+	"ledger/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer returns the API over an in-memory store and an admin key
+// for it.
+func newTestServer(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	store := memory.NewStore()
+	rates, err := services.NewStaticRateProvider(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewLedgerService(store, &config.LedgerConfig{
+		Workers:         1,
+		QueueSize:       10,
+		IdempotencyTTL:  time.Hour,
+		HoldDefaultTTL:  time.Hour,
+		TransferTimeout: 5 * time.Second,
+		BatchMaxItems:   10,
+	}, rates)
+	t.Cleanup(service.Shutdown)
+
+	scoped := store.WithContext(tenant.WithID(context.Background(), models.NewID()))
+	_, token, err := services.CreateAPIKey(scoped, "test", []string{"admin", "accounts:read", "accounts:write", "transfers:read", "transfers:write", "transfers:reverse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router.SetupRoutes(handler.NewLedgerHandler(service)), token
+}
+
+func do(t *testing.T, api http.Handler, token, method, path, body string) (int, utils.Problem) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+
+	var problem utils.Problem
+	if rec.Code >= 400 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code, problem
+}
+
+// IDs that are not UUIDs are bad requests, not lookups: Postgres would
+// refuse to compare them with its UUID columns.
+func TestMalformedIDs(t *testing.T) {
+	api, token := newTestServer(t)
+	id := models.NewID()
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/v1/accounts/not-a-uuid", ""},
+		{"GET", "/v1/accounts/not-a-uuid/balance", ""},
+		{"GET", "/v1/accounts/1234/audit-log", ""},
+		{"POST", "/v1/transactions/x/reverse", `{}`},
+		{"GET", "/v1/transactions?account_id=x", ""},
+		{"GET", "/v1/transactions?cursor=" + base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2024-01-01T00:00:00Z","id":"x"}`)), ""},
+		{"GET", "/v1/transfers/x", ""},
+		{"GET", "/v1/holds/x", ""},
+		{"POST", "/v1/holds/x/void", ""},
+		{"GET", "/v1/journal-entries/x", ""},
+		{"GET", "/v1/webhooks/x", ""},
+		{"GET", "/v1/audit/verify?account_id=x", ""},
+		{"POST", "/v1/transactions", `{"from_account_id":"x","to_account_id":"` + id + `","amount":"1.00"}`},
+		{"POST", "/v1/transactions", `{"from_account_id":"` + id + `","to_account_id":"x","amount":"1.00"}`},
+		{"POST", "/v1/holds", `{"account_id":"x","amount":"1.00"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			status, problem := do(t, api, token, tt.method, tt.path, tt.body)
+			if status != http.StatusBadRequest {
+				t.Errorf("status = %d (%s), want 400", status, problem.Code)
+			}
+		})
+	}
+
+	// A well-formed ID that names nothing is still not found.
+	if status, problem := do(t, api, token, "GET", "/v1/accounts/"+id, ""); status != http.StatusNotFound {
+		t.Errorf("GET of an unknown account = %d (%s), want 404", status, problem.Code)
+	}
+}
+
+// A listing asks for at most MaxAccountPageSize accounts, whatever limit the
+// client sends.
+func TestListAccountsCapsLimit(t *testing.T) {
+	api, token := newTestServer(t)
+	for i := 0; i <= services.MaxAccountPageSize; i++ {
+		if status, problem := do(t, api, token, "POST", "/v1/accounts", `{"owner_name":"test"}`); status != http.StatusCreated {
+			t.Fatalf("POST /v1/accounts = %d (%s), want 201", status, problem.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/v1/accounts?limit=1000", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+
+	var page struct {
+		Accounts []models.Account `json:"accounts"`
+		Limit    int              `json:"limit"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusOK || page.Limit != services.MaxAccountPageSize || len(page.Accounts) != services.MaxAccountPageSize {
+		t.Errorf("GET /v1/accounts?limit=1000 = %d with %d accounts and limit %d, want %d of each", rec.Code, len(page.Accounts), page.Limit, services.MaxAccountPageSize)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"ledger/internal/models"
+	"ledger/internal/utils"
+	"net/http"
+	"time"
+)
+
+type CreateHoldRequest struct {
+	AccountID   string        `json:"account_id" validate:"required,uuid"`
+	Amount      models.Amount `json:"amount" validate:"required,gt=0"`
+	Description string        `json:"description" validate:"max=255"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+}
+
+type CaptureHoldRequest struct {
+	ToAccountID string        `json:"to_account_id" validate:"required,uuid"`
+	Amount      models.Amount `json:"amount" validate:"gte=0"`
+	Description string        `json:"description" validate:"max=255"`
+}
+
+func (h *LedgerHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	data := &CreateHoldRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	hold, err := h.LedgerService.CreateHold(r.Context(), data.AccountID, data.Amount, data.Description, data.ExpiresAt)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusCreated, hold)
+}
+
+func (h *LedgerHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := h.urlID(w, r, "holdID", "hold_id")
+	if !ok {
+		return
+	}
+
+	hold, err := h.LedgerService.GetHold(r.Context(), holdID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, hold)
+}
+
+func (h *LedgerHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := h.urlID(w, r, "holdID", "hold_id")
+	if !ok {
+		return
+	}
+
+	data := &CaptureHoldRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	hold, err := h.LedgerService.CaptureHold(r.Context(), holdID, data.ToAccountID, data.Amount, data.Description)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, hold)
+}
+
+func (h *LedgerHandler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := h.urlID(w, r, "holdID", "hold_id")
+	if !ok {
+		return
+	}
+
+	hold, err := h.LedgerService.VoidHold(r.Context(), holdID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, hold)
+}
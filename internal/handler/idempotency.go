@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"net/http"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// idempotency reads the Idempotency-Key header of r. When the key was already
+// used it writes the stored or error response and reports done.
+func (h *LedgerHandler) idempotency(w http.ResponseWriter, r *http.Request) (*services.Idempotency, bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, false
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		badRequest(w, r, "Idempotency-Key is too long")
+		return nil, true
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		badRequest(w, r, "error reading body: "+err.Error())
+		return nil, true
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	fingerprint := requestFingerprint(r, body)
+
+	record, err := h.LedgerService.FindIdempotentResponse(r.Context(), key, fingerprint)
+	if err != nil {
+		errorResponse(w, r, err)
+		return nil, true
+	}
+
+	if record != nil {
+		writeIdempotentReplay(w, record)
+		return nil, true
+	}
+
+	return &services.Idempotency{Key: key, Fingerprint: fingerprint}, false
+}
+
+// idempotencyConflict handles err when a concurrent request with the same key
+// won the race, replaying its response once it is available.
+func (h *LedgerHandler) idempotencyConflict(w http.ResponseWriter, r *http.Request, idem *services.Idempotency, err error) bool {
+	if idem == nil || !errors.Is(err, services.ErrIdempotencyKeyConflict) {
+		return false
+	}
+
+	record, findErr := h.LedgerService.FindIdempotentResponse(r.Context(), idem.Key, idem.Fingerprint)
+	switch {
+	case errors.Is(findErr, services.ErrIdempotencyKeyReused):
+		errorResponse(w, r, findErr)
+	case findErr == nil && record != nil:
+		writeIdempotentReplay(w, record)
+	default:
+		errorResponse(w, r, err)
+	}
+	return true
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, body); err == nil {
+		body = compacted.Bytes()
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func writeIdempotentReplay(w http.ResponseWriter, record *models.IdempotencyKey) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.ResponseBody)
+}
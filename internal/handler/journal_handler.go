@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"ledger/internal/utils"
+	"net/http"
+)
+
+func (h *LedgerHandler) GetJournalEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, ok := h.urlID(w, r, "entryID", "entry_id")
+	if !ok {
+		return
+	}
+
+	entry, err := h.LedgerService.GetJournalEntry(r.Context(), entryID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, entry)
+}
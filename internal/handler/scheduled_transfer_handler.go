@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"ledger/internal/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type RecurrenceRequest struct {
+	Frequency  models.ScheduleFrequency `json:"frequency" validate:"required,oneof=daily weekly monthly cron"`
+	DayOfMonth int                      `json:"day_of_month" validate:"omitempty,min=1,max=31"`
+	Cron       string                   `json:"cron" validate:"max=100"`
+	StartAt    time.Time                `json:"start_at"`
+	EndAt      *time.Time               `json:"end_at"`
+}
+
+type CreateScheduledTransferRequest struct {
+	FromAccountID string               `json:"from_account_id" validate:"required,uuid"`
+	ToAccountID   string               `json:"to_account_id" validate:"required,uuid"`
+	Amount        models.Amount        `json:"amount" validate:"required,gt=0"`
+	Description   string               `json:"description" validate:"max=255"`
+	RunAt         *time.Time           `json:"run_at" validate:"required_without=Recurrence,excluded_with=Recurrence"`
+	Recurrence    *RecurrenceRequest   `json:"recurrence"`
+	CatchUp       models.CatchUpPolicy `json:"catch_up" validate:"omitempty,oneof=all latest skip"`
+}
+
+func (h *LedgerHandler) CreateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	idem, done := h.idempotency(w, r)
+	if done {
+		return
+	}
+
+	data := &CreateScheduledTransferRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return http.StatusCreated, result
+		}
+	}
+
+	req := services.ScheduleRequest{
+		TransferRequest: services.TransferRequest{
+			FromAccountID: data.FromAccountID,
+			ToAccountID:   data.ToAccountID,
+			Amount:        data.Amount,
+			Description:   data.Description,
+		},
+		CatchUp: data.CatchUp,
+	}
+	if data.Recurrence != nil {
+		req.Frequency = data.Recurrence.Frequency
+		req.DayOfMonth = data.Recurrence.DayOfMonth
+		req.Cron = data.Recurrence.Cron
+		req.StartAt = data.Recurrence.StartAt
+		req.EndAt = data.Recurrence.EndAt
+	} else {
+		req.Frequency = models.ScheduleFrequencyOnce
+		req.StartAt = *data.RunAt
+	}
+
+	schedule, err := h.LedgerService.CreateScheduledTransfer(r.Context(), req, idem)
+	if err != nil {
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusCreated, schedule)
+}
+
+func (h *LedgerHandler) GetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := h.urlID(w, r, "scheduleID", "schedule_id")
+	if !ok {
+		return
+	}
+
+	schedule, err := h.LedgerService.GetScheduledTransfer(r.Context(), scheduleID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, schedule)
+}
+
+func (h *LedgerHandler) ListScheduledTransferRuns(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := h.urlID(w, r, "scheduleID", "schedule_id")
+	if !ok {
+		return
+	}
+
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	limit := 10
+	offset := 0
+
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	runs, err := h.LedgerService.ListScheduledTransferRuns(r.Context(), scheduleID, limit, offset)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"runs":   runs,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *LedgerHandler) CancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	scheduleID, ok := h.urlID(w, r, "scheduleID", "schedule_id")
+	if !ok {
+		return
+	}
+
+	schedule, err := h.LedgerService.CancelScheduledTransfer(r.Context(), scheduleID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, schedule)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/pdf"
+	"ledger/internal/services"
+	"ledger/internal/utils"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mediaTypeJSON = "application/json"
+	mediaTypeCSV  = "text/csv"
+	mediaTypePDF  = "application/pdf"
+)
+
+var statementFormats = map[string]string{
+	"json": mediaTypeJSON,
+	"csv":  mediaTypeCSV,
+	"pdf":  mediaTypePDF,
+}
+
+func (h *LedgerHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	accountID, ok := h.urlID(w, r, "accountID", "account_id")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	mediaType := negotiateStatementFormat(r)
+	if mediaType == "" {
+		utils.ProblemResponse(w, r, http.StatusNotAcceptable, "not_acceptable", "statements are available as application/json, text/csv or application/pdf")
+		return
+	}
+
+	now := time.Now().UTC()
+	to := now
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := parseDay(toStr)
+		if err != nil {
+			badRequest(w, r, "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return
+		}
+		to = parsed
+	}
+
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := parseDay(fromStr)
+		if err != nil {
+			badRequest(w, r, "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+			return
+		}
+		from = parsed
+	}
+
+	statement, err := h.LedgerService.GetStatement(r.Context(), accountID, from, to)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	switch mediaType {
+	case mediaTypeCSV:
+		setStatementAttachment(w, statement, "csv")
+		w.Header().Set("Content-Type", mediaTypeCSV+"; charset=utf-8")
+		writeStatementCSV(w, statement)
+	case mediaTypePDF:
+		setStatementAttachment(w, statement, "pdf")
+		w.Header().Set("Content-Type", mediaTypePDF)
+		statementPDF(statement).WriteTo(w)
+	default:
+		utils.SuccessResponse(w, r, http.StatusOK, statementResponse(statement))
+	}
+}
+
+// negotiateStatementFormat picks the statement media type from the format
+// query parameter or, failing that, the Accept header. It returns "" when
+// the client accepts none of them.
+func negotiateStatementFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return statementFormats[strings.ToLower(format)]
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return mediaTypeJSON
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case mediaTypeJSON, mediaTypeCSV, mediaTypePDF:
+			candidate = mediaType
+		case "*/*", "application/*":
+			candidate = mediaTypeJSON
+		case "text/*":
+			candidate = mediaTypeCSV
+		}
+		if candidate != "" && q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	return best
+}
+
+func setStatementAttachment(w http.ResponseWriter, statement *services.Statement, extension string) {
+	filename := fmt.Sprintf("statement-%s-%s-%s.%s", statement.AccountID,
+		statement.From.Format(time.DateOnly), statement.To.Format(time.DateOnly), extension)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+func statementResponse(statement *services.Statement) map[string]interface{} {
+	lines := make([]map[string]interface{}, len(statement.Lines))
+	for i, l := range statement.Lines {
+		lines[i] = map[string]interface{}{
+			"transaction_id":   l.TransactionID,
+			"journal_entry_id": l.JournalEntryID,
+			"date":             l.Date,
+			"description":      l.Description,
+			"type":             l.Type,
+			"amount":           l.Amount,
+			"running_balance":  l.RunningBalance,
+		}
+	}
+
+	return map[string]interface{}{
+		"account_id":      statement.AccountID,
+		"owner_name":      statement.OwnerName,
+		"currency":        statement.Currency,
+		"from":            statement.From.Format(time.DateOnly),
+		"to":              statement.To.Format(time.DateOnly),
+		"opening_balance": statement.OpeningBalance,
+		"closing_balance": statement.ClosingBalance,
+		"total_debits":    statement.TotalDebits,
+		"total_credits":   statement.TotalCredits,
+		"lines":           lines,
+	}
+}
+
+// writeStatementCSV writes one row per posting between an opening balance
+// row and a totals row that carries the closing balance.
+func writeStatementCSV(w http.ResponseWriter, statement *services.Statement) {
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "description", "transaction_id", "journal_entry_id", "debit", "credit", "balance"})
+	out.Write([]string{statement.From.Format(time.DateOnly), "Opening balance", "", "", "", "", statement.OpeningBalance.String()})
+
+	for _, l := range statement.Lines {
+		debit, credit := statementColumns(l)
+		out.Write([]string{l.Date.UTC().Format(time.RFC3339), l.Description, l.TransactionID, l.JournalEntryID, debit, credit, l.RunningBalance.String()})
+	}
+
+	out.Write([]string{statement.To.Format(time.DateOnly), "Closing balance", "", "",
+		statement.TotalDebits.String(), statement.TotalCredits.String(), statement.ClosingBalance.String()})
+	out.Flush()
+}
+
+func statementColumns(l services.StatementLine) (string, string) {
+	if l.Type == models.TransactionTypeDebit {
+		return l.Amount.String(), ""
+	}
+	return "", l.Amount.String()
+}
+
+// Layout of the PDF statement, in points.
+const (
+	pdfMargin     = 40.0
+	pdfFontSize   = 9.0
+	pdfTitleSize  = 14.0
+	pdfLineHeight = 13.0
+
+	pdfColDate        = pdfMargin
+	pdfColDescription = pdfMargin + 11*pdfFontSize*0.6
+	pdfColDebitEnd    = 405.0
+	pdfColCreditEnd   = 480.0
+	pdfColBalanceEnd  = pdf.PageWidth - pdfMargin
+
+	pdfDescriptionChars = 38
+)
+
+func statementPDF(statement *services.Statement) *pdf.Document {
+	doc := pdf.New()
+
+	var page *pdf.Page
+	var y float64
+	newPage := func() {
+		page = doc.AddPage()
+		y = pdf.PageHeight - pdfMargin
+
+		page.Text(pdfMargin, y, pdfTitleSize, "Account statement")
+		y -= 2 * pdfLineHeight
+		page.Text(pdfMargin, y, pdfFontSize, "Account:  "+statement.AccountID)
+		y -= pdfLineHeight
+		page.Text(pdfMargin, y, pdfFontSize, "Owner:    "+statement.OwnerName)
+		y -= pdfLineHeight
+		page.Text(pdfMargin, y, pdfFontSize, fmt.Sprintf("Period:   %s to %s (%s)",
+			statement.From.Format(time.DateOnly), statement.To.Format(time.DateOnly), statement.Currency))
+		y -= 2 * pdfLineHeight
+
+		page.Text(pdfColDate, y, pdfFontSize, "Date")
+		page.Text(pdfColDescription, y, pdfFontSize, "Description")
+		page.TextRight(pdfColDebitEnd, y, pdfFontSize, "Debit")
+		page.TextRight(pdfColCreditEnd, y, pdfFontSize, "Credit")
+		page.TextRight(pdfColBalanceEnd, y, pdfFontSize, "Balance")
+		y -= 4
+		page.Line(pdfMargin, y, pdfColBalanceEnd, y)
+		y -= pdfLineHeight
+	}
+	row := func(date, description, debit, credit, balance string) {
+		if y < pdfMargin+pdfLineHeight {
+			newPage()
+		}
+		if runes := []rune(description); len(runes) > pdfDescriptionChars {
+			description = string(runes[:pdfDescriptionChars-3]) + "..."
+		}
+		page.Text(pdfColDate, y, pdfFontSize, date)
+		page.Text(pdfColDescription, y, pdfFontSize, description)
+		page.TextRight(pdfColDebitEnd, y, pdfFontSize, debit)
+		page.TextRight(pdfColCreditEnd, y, pdfFontSize, credit)
+		page.TextRight(pdfColBalanceEnd, y, pdfFontSize, balance)
+		y -= pdfLineHeight
+	}
+
+	newPage()
+	row(statement.From.Format(time.DateOnly), "Opening balance", "", "", statement.OpeningBalance.String())
+	for _, l := range statement.Lines {
+		debit, credit := statementColumns(l)
+		row(l.Date.UTC().Format(time.DateOnly), l.Description, debit, credit, l.RunningBalance.String())
+	}
+
+	if y < pdfMargin+2*pdfLineHeight {
+		newPage()
+	}
+	page.Line(pdfMargin, y+pdfLineHeight-4, pdfColBalanceEnd, y+pdfLineHeight-4)
+	row(statement.To.Format(time.DateOnly), "Closing balance",
+		statement.TotalDebits.String(), statement.TotalCredits.String(), statement.ClosingBalance.String())
+
+	return doc
+}
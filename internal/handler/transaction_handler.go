@@ -1,95 +1,187 @@
 package handler
 
 import (
+	"ledger/internal/models"
+	"ledger/internal/services"
 	"ledger/internal/utils"
 	"net/http"
 	"strconv"
-
-	"github.com/go-chi/chi/v5"
+	"time"
 )
 
+type ReverseTransactionRequest struct {
+	Amount models.Amount `json:"amount" validate:"gte=0"`
+}
+
 type CreateTransactionRequest struct {
-	FromAccountID string  `json:"from_account_id" validate:"required"`
-	ToAccountID   string  `json:"to_account_id" validate:"required"`
-	Amount        float64 `json:"amount" validate:"required,gt=0"`
-	Description   string  `json:"description" validate:"max=255"`
+	FromAccountID string        `json:"from_account_id" validate:"required,uuid"`
+	ToAccountID   string        `json:"to_account_id" validate:"required,uuid"`
+	Amount        models.Amount `json:"amount" validate:"required,gt=0"`
+	Description   string        `json:"description" validate:"max=255"`
+	QuoteID       string        `json:"quote_id" validate:"omitempty,uuid"`
 }
 
 func (h *LedgerHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	idem, done := h.idempotency(w, r)
+	if done {
+		return
+	}
+
 	data := &CreateTransactionRequest{}
 
 	if utils.DecodeAndValidate(w, r, h.Validate, data) {
 		return
 	}
 
-	err := h.LedgerService.CreateTransaction(
-		data.FromAccountID,
-		data.ToAccountID,
-		data.Amount,
-		data.Description,
-	)
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return http.StatusCreated, transactionCreatedResponse(data, result.(*models.JournalEntry))
+		}
+	}
+
+	entry, err := h.LedgerService.CreateTransaction(r.Context(), services.TransferRequest{
+		FromAccountID: data.FromAccountID,
+		ToAccountID:   data.ToAccountID,
+		Amount:        data.Amount,
+		Description:   data.Description,
+		QuoteID:       data.QuoteID,
+	}, idem)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
 		return
 	}
 
-	utils.SuccessResponse(w, r, http.StatusCreated, map[string]interface{}{
-		"message":         "transaction created",
-		"from_account_id": data.FromAccountID,
-		"to_account_id":   data.ToAccountID,
-		"amount":          data.Amount,
-	})
+	utils.SuccessResponse(w, r, http.StatusCreated, transactionCreatedResponse(data, entry))
+}
+
+func transactionCreatedResponse(data *CreateTransactionRequest, entry *models.JournalEntry) map[string]interface{} {
+	response := map[string]interface{}{
+		"message":          "transaction created",
+		"journal_entry_id": entry.ID,
+		"from_account_id":  data.FromAccountID,
+		"to_account_id":    data.ToAccountID,
+		"amount":           data.Amount,
+		"currency":         entry.Postings[0].Currency,
+	}
+
+	if data.QuoteID != "" {
+		for _, p := range entry.Postings {
+			if p.AccountID == data.ToAccountID && p.Type == models.TransactionTypeCredit {
+				response["quote_id"] = data.QuoteID
+				response["converted_amount"] = p.Amount
+				response["converted_currency"] = p.Currency
+			}
+		}
+	}
+	return response
 }
 
 func (h *LedgerHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	accountID := r.URL.Query().Get("account_id")
 	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
 
 	limit := 10
-	offset := 0
 
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+			limit = min(l, services.MaxTransactionPageSize)
 		}
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
+	if r.URL.Query().Has("offset") {
+		badRequest(w, r, "offset is not supported; page with next_cursor and prev_cursor")
+		return
+	}
+	if accountID != "" && h.Validate.Var(accountID, "uuid") != nil {
+		badRequest(w, r, "account_id must be a uuid")
+		return
+	}
+
+	filter := services.TransactionFilter{
+		AccountID: accountID,
+		Type:      models.TransactionType(r.URL.Query().Get("type")),
+	}
+	if filter.Type != "" && filter.Type != models.TransactionTypeDebit && filter.Type != models.TransactionTypeCredit {
+		badRequest(w, r, "type must be DEBIT or CREDIT")
+		return
+	}
+	for param, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := r.URL.Query().Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				badRequest(w, r, param+" must be an RFC 3339 timestamp")
+				return
+			}
+			*bound = t
 		}
 	}
 
-	transactions, err := h.LedgerService.ListTransactions(accountID, limit, offset)
+	page, err := h.LedgerService.ListTransactions(r.Context(), filter, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusInternalServerError, err.Error())
+		errorResponse(w, r, err)
 		return
 	}
 
 	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
-		"transactions": transactions,
-		"limit":        limit,
-		"offset":       offset,
+		"transactions": page.Transactions,
+		"limit":        page.Limit,
+		"next_cursor":  cursorOrNull(page.NextCursor),
+		"prev_cursor":  cursorOrNull(page.PrevCursor),
 	})
 }
 
+// cursorOrNull writes a missing cursor as null.
+func cursorOrNull(cursor string) interface{} {
+	if cursor == "" {
+		return nil
+	}
+	return cursor
+}
+
 func (h *LedgerHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
-	transactionID := chi.URLParam(r, "transactionID")
+	transactionID, ok := h.urlID(w, r, "transactionID", "transaction_id")
+	if !ok {
+		return
+	}
 
-	if transactionID == "" {
-		utils.ErrorResponse(w, r, http.StatusBadRequest, "transaction_id is required")
+	idem, done := h.idempotency(w, r)
+	if done {
 		return
 	}
 
-	err := h.LedgerService.ReverseTransaction(transactionID)
+	data := &ReverseTransactionRequest{}
+
+	if r.ContentLength != 0 && utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return http.StatusOK, reversalResponse(transactionID, result.(*models.JournalEntry))
+		}
+	}
+
+	reversal, err := h.LedgerService.ReverseTransaction(r.Context(), transactionID, data.Amount, idem)
 	if err != nil {
-		utils.ErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
 		return
 	}
 
-	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
-		"message":        "transaction reversed",
-		"transaction_id": transactionID,
-	})
+	utils.SuccessResponse(w, r, http.StatusOK, reversalResponse(transactionID, reversal))
+}
+
+func reversalResponse(transactionID string, reversal *models.JournalEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"message":          "transaction reversed",
+		"transaction_id":   transactionID,
+		"journal_entry_id": reversal.ID,
+		"reversal_of":      reversal.ReversalOfID,
+		"postings":         reversal.Postings,
+	}
 }
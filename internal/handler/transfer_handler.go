@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"ledger/internal/utils"
+	"net/http"
+	"strings"
+)
+
+const preferRespondAsync = "respond-async"
+
+func (h *LedgerHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	idem, done := h.idempotency(w, r)
+	if done {
+		return
+	}
+
+	data := &CreateTransactionRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	async := prefersAsync(r)
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return transferStatusCode(result.(*models.Transfer)), result
+		}
+	}
+
+	transfer, err := h.LedgerService.SubmitTransfer(r.Context(), services.TransferRequest{
+		FromAccountID: data.FromAccountID,
+		ToAccountID:   data.ToAccountID,
+		Amount:        data.Amount,
+		Description:   data.Description,
+		QuoteID:       data.QuoteID,
+	}, async, idem)
+	if err != nil {
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/transfers/"+transfer.ID)
+	if async {
+		w.Header().Set("Preference-Applied", preferRespondAsync)
+	}
+	utils.SuccessResponse(w, r, transferStatusCode(transfer), transfer)
+}
+
+func (h *LedgerHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	transferID, ok := h.urlID(w, r, "transferID", "transfer_id")
+	if !ok {
+		return
+	}
+
+	transfer, err := h.LedgerService.GetTransfer(r.Context(), transferID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, transfer)
+}
+
+// prefersAsync reports whether the Prefer header asks for respond-async.
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(pref, ";")
+			if strings.EqualFold(strings.TrimSpace(token), preferRespondAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func transferStatusCode(transfer *models.Transfer) int {
+	switch transfer.Status {
+	case models.TransferStatusCompleted:
+		return http.StatusCreated
+	case models.TransferStatusFailed:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusAccepted
+	}
+}
+
+type CreateTransferBatchRequest struct {
+	Transfers []CreateTransactionRequest `json:"transfers" validate:"required,min=1,dive"`
+}
+
+func (h *LedgerHandler) CreateTransferBatch(w http.ResponseWriter, r *http.Request) {
+	idem, done := h.idempotency(w, r)
+	if done {
+		return
+	}
+
+	data := &CreateTransferBatchRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	if idem != nil {
+		idem.Response = func(result interface{}) (int, interface{}) {
+			return transferBatchStatusCode(result.(*models.TransferBatch)), result
+		}
+	}
+
+	reqs := make([]services.TransferRequest, len(data.Transfers))
+	for i, t := range data.Transfers {
+		reqs[i] = services.TransferRequest{
+			FromAccountID: t.FromAccountID,
+			ToAccountID:   t.ToAccountID,
+			Amount:        t.Amount,
+			Description:   t.Description,
+			QuoteID:       t.QuoteID,
+		}
+	}
+
+	batch, err := h.LedgerService.CreateTransferBatch(r.Context(), reqs, idem)
+	if err != nil {
+		if h.idempotencyConflict(w, r, idem, err) {
+			return
+		}
+		errorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/transfers/batches/"+batch.ID)
+	utils.SuccessResponse(w, r, transferBatchStatusCode(batch), batch)
+}
+
+func (h *LedgerHandler) GetTransferBatch(w http.ResponseWriter, r *http.Request) {
+	batchID, ok := h.urlID(w, r, "batchID", "batch_id")
+	if !ok {
+		return
+	}
+
+	batch, err := h.LedgerService.GetTransferBatch(r.Context(), batchID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, batch)
+}
+
+func transferBatchStatusCode(batch *models.TransferBatch) int {
+	if batch.Status == models.TransferBatchStatusFailed {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusCreated
+}
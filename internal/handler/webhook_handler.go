@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"ledger/internal/models"
+	"ledger/internal/services"
+	"ledger/internal/utils"
+	"net/http"
+	"strconv"
+)
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	EventTypes  []string `json:"event_types" validate:"dive,oneof=transfer.completed account.created transaction.reversed"`
+	Description string   `json:"description" validate:"max=255"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url" validate:"omitempty,url,max=2048"`
+	EventTypes  *[]string `json:"event_types" validate:"omitempty,dive,oneof=transfer.completed account.created transaction.reversed"`
+	Description *string   `json:"description" validate:"omitempty,max=255"`
+	Active      *bool     `json:"active"`
+}
+
+func (h *LedgerHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	data := &CreateWebhookRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	endpoint, err := h.LedgerService.CreateWebhook(r.Context(), data.URL, data.EventTypes, data.Description)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	// The secret is only ever returned here.
+	utils.SuccessResponse(w, r, http.StatusCreated, map[string]interface{}{
+		"id":          endpoint.ID,
+		"url":         endpoint.URL,
+		"event_types": endpoint.EventTypes,
+		"description": endpoint.Description,
+		"active":      endpoint.Active,
+		"secret":      endpoint.Secret,
+		"created_at":  endpoint.CreatedAt,
+	})
+}
+
+func (h *LedgerHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.LedgerService.ListWebhooks(r.Context())
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"webhooks": endpoints,
+	})
+}
+
+func (h *LedgerHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.urlID(w, r, "webhookID", "webhook_id")
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.LedgerService.GetWebhook(r.Context(), webhookID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, endpoint)
+}
+
+func (h *LedgerHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.urlID(w, r, "webhookID", "webhook_id")
+	if !ok {
+		return
+	}
+
+	data := &UpdateWebhookRequest{}
+
+	if utils.DecodeAndValidate(w, r, h.Validate, data) {
+		return
+	}
+
+	endpoint, err := h.LedgerService.UpdateWebhook(r.Context(), webhookID, services.WebhookUpdate{
+		URL:         data.URL,
+		EventTypes:  data.EventTypes,
+		Description: data.Description,
+		Active:      data.Active,
+	})
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, endpoint)
+}
+
+func (h *LedgerHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.urlID(w, r, "webhookID", "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := h.LedgerService.DeleteWebhook(r.Context(), webhookID); err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *LedgerHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID, ok := h.urlID(w, r, "webhookID", "webhook_id")
+	if !ok {
+		return
+	}
+
+	status := models.WebhookDeliveryStatus(r.URL.Query().Get("status"))
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	limit := 10
+	offset := 0
+
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	deliveries, err := h.LedgerService.ListWebhookDeliveries(r.Context(), webhookID, status, limit, offset)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+func (h *LedgerHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	deliveryID, ok := h.urlID(w, r, "deliveryID", "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.LedgerService.RedeliverWebhook(r.Context(), deliveryID)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	utils.SuccessResponse(w, r, http.StatusAccepted, delivery)
+}
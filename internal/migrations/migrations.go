@@ -0,0 +1,387 @@
+// Package migrations keeps the database schema in numbered up and down SQL
+// migrations embedded in the binary, one sequence per database driver, and
+// records the ones applied in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"ledger/internal/repository"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// advisoryLockID is the Postgres advisory lock held while migrating, so that
+// of several processes starting at once only one migrates and the others
+// wait for it.
+const advisoryLockID = 7346918205
+
+var (
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrSchemaBehind   = errors.New("database schema is behind")
+)
+
+// schemaMigrationsTable is the bookkeeping table, by driver.
+var schemaMigrationsTable = map[string]string{
+	"postgres": `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at timestamptz NOT NULL
+)`,
+	"sqlite": `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version integer PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at datetime NOT NULL
+)`,
+}
+
+// hooks are the steps SQL cannot express, by driver and version. A hook runs
+// in its migration's transaction, before the up SQL.
+var hooks = map[string]map[int64]func(tx *gorm.DB) error{
+	"postgres": {3: repository.BackfillHashChain},
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+
+	up     string
+	down   string
+	before func(tx *gorm.DB) error
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status is a migration and when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	driver     string
+	migrations []Migration
+}
+
+// New returns a Migrator for the migrations of db's driver.
+func New(db *gorm.DB) (*Migrator, error) {
+	driver := db.Dialector.Name()
+	migrations, err := load(driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// load reads the migrations of driver, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, in version order.
+func load(driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok {
+			continue
+		}
+		base, direction, ok := cutDirection(base)
+		if !ok {
+			return nil, fmt.Errorf("migration %s is neither .up.sql nor .down.sql", entry.Name())
+		}
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s does not start with a version", entry.Name())
+		}
+
+		body, err := fs.ReadFile(files, driver+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: name, before: hooks[driver][version]}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration, base, version)
+		}
+		if direction == "up" {
+			migration.up = string(body)
+		} else {
+			migration.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func cutDirection(base string) (string, string, bool) {
+	if name, ok := strings.CutSuffix(base, ".up"); ok {
+		return name, "up", true
+	}
+	if name, ok := strings.CutSuffix(base, ".down"); ok {
+		return name, "down", true
+	}
+	return base, "", false
+}
+
+// Latest is the version of the newest migration.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every migration, oldest first, with when it was applied.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if record, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &record.AppliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind if any migration has not been applied.
+func (m *Migrator) Check() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.String())
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migrations pending (%s)", ErrSchemaBehind, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down() error {
+	return m.locked(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		var latest int64
+		for version := range applied {
+			latest = max(latest, version)
+		}
+		if latest == 0 {
+			return nil
+		}
+
+		migration, ok := m.find(latest)
+		if !ok {
+			return fmt.Errorf("%w: %d is applied but unknown to this build", ErrUnknownVersion, latest)
+		}
+		return m.revert(conn, migration)
+	})
+}
+
+// To applies or reverts migrations until exactly those up to version are
+// applied. Version 0 reverts them all.
+func (m *Migrator) To(version int64) error {
+	if version != 0 {
+		if _, ok := m.find(version); !ok {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return m.locked(func(conn *gorm.DB) error {
+		return m.migrate(conn, version)
+	})
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked runs fn on a single connection holding the migration lock, with
+// the schema_migrations table in place. SQLite needs no lock of its own:
+// every migration takes the database's write lock when it begins.
+func (m *Migrator) locked(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if m.driver == "postgres" {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockID).Error; err != nil {
+				return fmt.Errorf("failed to take the migration lock: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockID)
+		}
+
+		if err := conn.Exec(schemaMigrationsTable[m.driver]).Error; err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		return fn(conn)
+	})
+}
+
+// migrate applies the pending migrations up to target, oldest first, then
+// reverts the applied ones past it, newest first.
+func (m *Migrator) migrate(conn *gorm.DB, target int64) error {
+	applied, err := m.applied(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+			if err := m.apply(conn, migration); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > target {
+			if err := m.revert(conn, migration); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if done, err := isApplied(tx, migration.Version); err != nil || done {
+			return err
+		}
+		if migration.before != nil {
+			if err := migration.before(tx); err != nil {
+				return err
+			}
+		}
+		if err := execScript(tx, migration.up); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	}
+
+	slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+func (m *Migrator) revert(conn *gorm.DB, migration Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if done, err := isApplied(tx, migration.Version); err != nil || !done {
+			return err
+		}
+		if err := execScript(tx, migration.down); err != nil {
+			return err
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %s: %w", migration, err)
+	}
+
+	slog.Info("Reverted migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// applied returns the applied migrations by version. A database without a
+// schema_migrations table has none.
+func (m *Migrator) applied(db *gorm.DB) (map[int64]schemaMigration, error) {
+	if !db.Migrator().HasTable(schemaMigration{}.TableName()) {
+		return map[int64]schemaMigration{}, nil
+	}
+
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// isApplied checks again inside a migration's transaction, so that a
+// migration another process applied in the meantime is not run twice.
+func isApplied(tx *gorm.DB, version int64) (bool, error) {
+	var count int64
+	err := tx.Model(&schemaMigration{}).Where("version = ?", version).Count(&count).Error
+	return count > 0, err
+}
+
+// execScript runs the statements of a migration file. A file of comments
+// alone, for a migration with nothing to undo, runs nothing.
+func execScript(tx *gorm.DB, script string) error {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return tx.Exec(script).Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS transactions_journal_balanced ON transactions;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
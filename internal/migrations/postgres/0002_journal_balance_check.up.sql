@@ -0,0 +1,41 @@
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+DECLARE
+	entry_id uuid;
+	unbalanced record;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		entry_id := OLD.journal_entry_id;
+	ELSE
+		entry_id := NEW.journal_entry_id;
+	END IF;
+
+	IF entry_id IS NULL THEN
+		RETURN NULL;
+	END IF;
+
+	SELECT currency, SUM(CASE WHEN type = 'CREDIT' THEN amount ELSE -amount END) AS total
+	INTO unbalanced
+	FROM transactions
+	WHERE journal_entry_id = entry_id
+	GROUP BY currency
+	HAVING SUM(CASE WHEN type = 'CREDIT' THEN amount ELSE -amount END) <> 0
+	LIMIT 1;
+
+	IF FOUND THEN
+		RAISE EXCEPTION 'journal entry % is unbalanced by % %', entry_id, unbalanced.total, unbalanced.currency;
+	END IF;
+
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS transactions_journal_balanced ON transactions;
+CREATE CONSTRAINT TRIGGER transactions_journal_balanced
+	AFTER INSERT OR UPDATE OR DELETE ON transactions
+	DEFERRABLE INITIALLY DEFERRED
+	FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- The append-only triggers come back in the next migration, once postings
+-- written before the hash chain existed have been chained.
+DROP TRIGGER IF EXISTS transactions_append_only ON transactions;
+DROP TRIGGER IF EXISTS transactions_no_truncate ON transactions;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS transactions_no_truncate ON transactions;
+DROP TRIGGER IF EXISTS transactions_append_only ON transactions;
+DROP FUNCTION IF EXISTS reject_ledger_change();
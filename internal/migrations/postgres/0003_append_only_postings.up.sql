@@ -0,0 +1,18 @@
+-- Postings written before postings were hashed are chained before this
+-- runs; see BackfillHashChain.
+
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '% is append-only: % is not allowed', TG_TABLE_NAME, TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS transactions_append_only ON transactions;
+CREATE TRIGGER transactions_append_only
+	BEFORE UPDATE OR DELETE ON transactions
+	FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+DROP TRIGGER IF EXISTS transactions_no_truncate ON transactions;
+CREATE TRIGGER transactions_no_truncate
+	BEFORE TRUNCATE ON transactions
+	FOR EACH STATEMENT EXECUTE FUNCTION reject_ledger_change();
@@ -0,0 +1 @@
+-- The snapshots stay: every posting since has kept them up to date.
@@ -0,0 +1,22 @@
+-- Builds balance snapshots from the postings made before snapshots were
+-- maintained. It does nothing once any snapshot exists, since from then on
+-- every posting keeps them up to date.
+
+INSERT INTO balance_snapshots
+	(account_id, day, opening_balance, closing_balance, total_debits, total_credits, updated_at)
+SELECT account_id, day,
+	SUM(net) OVER running - net,
+	SUM(net) OVER running,
+	debits, credits, NOW()
+FROM (
+	SELECT account_id,
+		(created_at AT TIME ZONE 'UTC')::date AS day,
+		SUM(CASE WHEN type = 'CREDIT' THEN amount ELSE -amount END) AS net,
+		SUM(CASE WHEN type = 'DEBIT' THEN amount ELSE 0 END) AS debits,
+		SUM(CASE WHEN type = 'CREDIT' THEN amount ELSE 0 END) AS credits
+	FROM transactions
+	WHERE NOT EXISTS (SELECT 1 FROM balance_snapshots)
+	GROUP BY account_id, day
+) daily
+WINDOW running AS (PARTITION BY account_id ORDER BY day)
+ON CONFLICT DO NOTHING;
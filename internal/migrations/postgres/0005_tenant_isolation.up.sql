@@ -0,0 +1,101 @@
+-- Within a transaction started through the repository, Postgres itself
+-- hides and refuses rows of other tenants, behind the repository's tenant
+-- callbacks. Sessions that set no tenant, like the background workers',
+-- see every row. Roles with BYPASSRLS, superusers included, are not subject
+-- to the policies.
+
+-- System accounts and idempotency keys used to be unique across the whole
+-- ledger; they are now unique per tenant.
+DROP INDEX IF EXISTS idx_accounts_system_owner;
+DO $$
+BEGIN
+	IF (SELECT array_length(conkey, 1) FROM pg_constraint WHERE conname = 'idempotency_keys_pkey') = 1 THEN
+		ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+		ALTER TABLE idempotency_keys ADD PRIMARY KEY (tenant_id, key);
+	END IF;
+END;
+$$;
+
+ALTER TABLE accounts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE accounts FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON accounts;
+CREATE POLICY tenant_isolation ON accounts
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE fx_quotes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE fx_quotes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON fx_quotes;
+CREATE POLICY tenant_isolation ON fx_quotes
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE holds ENABLE ROW LEVEL SECURITY;
+ALTER TABLE holds FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON holds;
+CREATE POLICY tenant_isolation ON holds
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE journal_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE journal_entries FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON journal_entries;
+CREATE POLICY tenant_isolation ON journal_entries
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox_events FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+CREATE POLICY tenant_isolation ON outbox_events
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE scheduled_transfers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scheduled_transfers FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_transfers;
+CREATE POLICY tenant_isolation ON scheduled_transfers
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE transactions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transactions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON transactions;
+CREATE POLICY tenant_isolation ON transactions
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE transfer_batches ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transfer_batches FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON transfer_batches;
+CREATE POLICY tenant_isolation ON transfer_batches
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE transfers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE transfers FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON transfers;
+CREATE POLICY tenant_isolation ON transfers
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+ALTER TABLE webhook_endpoints ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_endpoints FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON webhook_endpoints;
+CREATE POLICY tenant_isolation ON webhook_endpoints
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
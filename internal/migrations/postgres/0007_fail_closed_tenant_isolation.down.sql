@@ -0,0 +1,59 @@
+DROP POLICY IF EXISTS tenant_isolation ON accounts;
+CREATE POLICY tenant_isolation ON accounts
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON fx_quotes;
+CREATE POLICY tenant_isolation ON fx_quotes
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON holds;
+CREATE POLICY tenant_isolation ON holds
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON journal_entries;
+CREATE POLICY tenant_isolation ON journal_entries
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+CREATE POLICY tenant_isolation ON outbox_events
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_transfers;
+CREATE POLICY tenant_isolation ON scheduled_transfers
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON transactions;
+CREATE POLICY tenant_isolation ON transactions
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON transfer_batches;
+CREATE POLICY tenant_isolation ON transfer_batches
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON transfers;
+CREATE POLICY tenant_isolation ON transfers
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
+
+DROP POLICY IF EXISTS tenant_isolation ON webhook_endpoints;
+CREATE POLICY tenant_isolation ON webhook_endpoints
+	USING (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id))
+	WITH CHECK (coalesce(current_setting('app.tenant_id', true), '') IN ('', tenant_id));
@@ -0,0 +1,29 @@
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+ALTER TABLE audit_logs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON account_status_changes;
+ALTER TABLE account_status_changes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE account_status_changes DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_account_status_changes_tenant_id;
+ALTER TABLE account_status_changes DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON balance_snapshots;
+ALTER TABLE balance_snapshots NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE balance_snapshots DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_balance_snapshots_tenant_id;
+ALTER TABLE balance_snapshots DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_transfer_runs;
+ALTER TABLE scheduled_transfer_runs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE scheduled_transfer_runs DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_scheduled_transfer_runs_tenant_id;
+ALTER TABLE scheduled_transfer_runs DROP COLUMN IF EXISTS tenant_id;
+
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+ALTER TABLE webhook_deliveries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_webhook_deliveries_tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS tenant_id;
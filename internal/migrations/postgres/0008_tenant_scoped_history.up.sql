@@ -0,0 +1,61 @@
+-- Audit logs, account status changes, balance snapshots, scheduled
+-- transfer runs and webhook deliveries belong to the tenant of the account,
+-- schedule or endpoint they record, and are isolated like the rest of the
+-- tenant's rows. The existing rows take their tenant from that owner; the
+-- migration reads across tenants to find it.
+SELECT set_config('app.bypass_rls', 'on', true);
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE audit_logs SET tenant_id = accounts.tenant_id
+	FROM accounts WHERE audit_logs.entity_type = 'account' AND audit_logs.entity_id = accounts.id::text;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id ON audit_logs (tenant_id);
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_logs;
+CREATE POLICY tenant_isolation ON audit_logs
+	USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE account_status_changes ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE account_status_changes SET tenant_id = accounts.tenant_id
+	FROM accounts WHERE account_status_changes.account_id = accounts.id;
+CREATE INDEX IF NOT EXISTS idx_account_status_changes_tenant_id ON account_status_changes (tenant_id);
+ALTER TABLE account_status_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE account_status_changes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON account_status_changes;
+CREATE POLICY tenant_isolation ON account_status_changes
+	USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE balance_snapshots ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE balance_snapshots SET tenant_id = accounts.tenant_id
+	FROM accounts WHERE balance_snapshots.account_id = accounts.id;
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_tenant_id ON balance_snapshots (tenant_id);
+ALTER TABLE balance_snapshots ENABLE ROW LEVEL SECURITY;
+ALTER TABLE balance_snapshots FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON balance_snapshots;
+CREATE POLICY tenant_isolation ON balance_snapshots
+	USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE scheduled_transfer_runs ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE scheduled_transfer_runs SET tenant_id = scheduled_transfers.tenant_id
+	FROM scheduled_transfers WHERE scheduled_transfer_runs.scheduled_transfer_id = scheduled_transfers.id;
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfer_runs_tenant_id ON scheduled_transfer_runs (tenant_id);
+ALTER TABLE scheduled_transfer_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scheduled_transfer_runs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON scheduled_transfer_runs;
+CREATE POLICY tenant_isolation ON scheduled_transfer_runs
+	USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
+
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE webhook_deliveries SET tenant_id = webhook_endpoints.tenant_id
+	FROM webhook_endpoints WHERE webhook_deliveries.endpoint_id = webhook_endpoints.id;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant_id ON webhook_deliveries (tenant_id);
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries;
+CREATE POLICY tenant_isolation ON webhook_deliveries
+	USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on')
+	WITH CHECK (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.bypass_rls', true) = 'on');
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS balance_snapshots;
+DROP TABLE IF EXISTS scheduled_transfer_runs;
+DROP TABLE IF EXISTS scheduled_transfers;
+DROP TABLE IF EXISTS transfer_batches;
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS account_status_changes;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS accounts;
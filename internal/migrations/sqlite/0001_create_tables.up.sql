@@ -0,0 +1,279 @@
+-- The schema the ledger used to create with AutoMigrate. Every statement is
+-- conditional, so databases created that way take this migration unchanged.
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	owner_name varchar(100) NOT NULL,
+	currency char(3) NOT NULL DEFAULT 'USD',
+	status varchar(20) NOT NULL DEFAULT 'active',
+	balance decimal(15,2) NOT NULL DEFAULT '0',
+	held_balance decimal(15,2) NOT NULL DEFAULT '0',
+	overdraft_limit decimal(15,2) NOT NULL DEFAULT '0',
+	never_negative boolean NOT NULL DEFAULT false,
+	system boolean NOT NULL DEFAULT false,
+	created_at datetime,
+	updated_at datetime,
+	deleted_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON accounts (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_tenant_system_owner ON accounts (tenant_id, owner_name) WHERE system;
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant_id ON accounts (tenant_id);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	type varchar(20) NOT NULL,
+	description varchar(255),
+	reversal_of_id uuid,
+	created_by_key_id uuid,
+	created_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_created_by_key_id ON journal_entries (created_by_key_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_reversal_of_id ON journal_entries (reversal_of_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_tenant_id ON journal_entries (tenant_id);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	journal_entry_id uuid,
+	account_id uuid NOT NULL,
+	sequence integer NOT NULL DEFAULT 0,
+	type varchar(20) NOT NULL,
+	amount decimal(15,2) NOT NULL,
+	currency char(3) NOT NULL DEFAULT 'USD',
+	description varchar(255),
+	reversal_of_id uuid,
+	prev_hash varchar(64) NOT NULL DEFAULT '',
+	hash varchar(64) NOT NULL DEFAULT '',
+	created_by_key_id uuid,
+	created_at datetime,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_transactions_account FOREIGN KEY (account_id) REFERENCES accounts (id),
+	CONSTRAINT fk_journal_entries_postings FOREIGN KEY (journal_entry_id) REFERENCES journal_entries (id)
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_by_key_id ON transactions (created_by_key_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_reversal_of_id ON transactions (reversal_of_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_account_sequence ON transactions (account_id, sequence) WHERE sequence > 0;
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions (account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_journal_entry_id ON transactions (journal_entry_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_tenant_id ON transactions (tenant_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	tenant_id varchar(64) DEFAULT 'default',
+	key varchar(255),
+	fingerprint char(64) NOT NULL,
+	status_code integer NOT NULL,
+	response_body jsonb NOT NULL,
+	created_at datetime NOT NULL,
+	expires_at datetime NOT NULL,
+	PRIMARY KEY (tenant_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS fx_quotes (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	from_currency char(3) NOT NULL,
+	to_currency char(3) NOT NULL,
+	mid_rate decimal(24,12) NOT NULL,
+	rate decimal(24,12) NOT NULL,
+	expires_at datetime NOT NULL,
+	used_at datetime,
+	journal_entry_id uuid,
+	created_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_fx_quotes_tenant_id ON fx_quotes (tenant_id);
+
+CREATE TABLE IF NOT EXISTS holds (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	account_id uuid NOT NULL,
+	amount decimal(15,2) NOT NULL,
+	currency char(3) NOT NULL,
+	captured_amount decimal(15,2) NOT NULL DEFAULT '0',
+	status varchar(20) NOT NULL,
+	description varchar(255),
+	expires_at datetime NOT NULL,
+	journal_entry_id uuid,
+	created_at datetime,
+	updated_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_holds_expires_at ON holds (expires_at);
+CREATE INDEX IF NOT EXISTS idx_holds_status ON holds (status);
+CREATE INDEX IF NOT EXISTS idx_holds_account_id ON holds (account_id);
+CREATE INDEX IF NOT EXISTS idx_holds_tenant_id ON holds (tenant_id);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id uuid,
+	entity_type varchar(50) NOT NULL,
+	entity_id varchar(64) NOT NULL,
+	action varchar(50) NOT NULL,
+	changes jsonb NOT NULL,
+	reason varchar(255),
+	created_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+
+CREATE TABLE IF NOT EXISTS account_status_changes (
+	id uuid,
+	account_id uuid NOT NULL,
+	from_status varchar(20) NOT NULL,
+	to_status varchar(20) NOT NULL,
+	reason varchar(255) NOT NULL,
+	created_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_account_status_changes_account_id ON account_status_changes (account_id);
+
+CREATE TABLE IF NOT EXISTS transfers (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	from_account_id uuid NOT NULL,
+	to_account_id uuid NOT NULL,
+	amount decimal(15,2) NOT NULL,
+	description varchar(255),
+	quote_id uuid,
+	status varchar(20) NOT NULL,
+	failure_code varchar(50),
+	failure_reason varchar(255),
+	journal_entry_id uuid,
+	batch_id uuid,
+	batch_index integer,
+	created_by_key_id uuid,
+	created_at datetime,
+	updated_at datetime,
+	completed_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_transfers_batch_id ON transfers (batch_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_status ON transfers (status);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_account_id ON transfers (to_account_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_from_account_id ON transfers (from_account_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_tenant_id ON transfers (tenant_id);
+
+CREATE TABLE IF NOT EXISTS transfer_batches (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	status varchar(20) NOT NULL,
+	item_count integer NOT NULL,
+	failure_reason varchar(255),
+	created_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_transfer_batches_tenant_id ON transfer_batches (tenant_id);
+
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	from_account_id uuid NOT NULL,
+	to_account_id uuid NOT NULL,
+	amount decimal(15,2) NOT NULL,
+	description varchar(255),
+	frequency varchar(20) NOT NULL,
+	day_of_month integer,
+	cron_expression varchar(100),
+	start_at datetime NOT NULL,
+	end_at datetime,
+	catch_up varchar(20) NOT NULL,
+	status varchar(20) NOT NULL,
+	next_run_at datetime,
+	last_run_at datetime,
+	created_by_key_id uuid,
+	created_at datetime,
+	updated_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers (status, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_to_account_id ON scheduled_transfers (to_account_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_from_account_id ON scheduled_transfers (from_account_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_tenant_id ON scheduled_transfers (tenant_id);
+
+CREATE TABLE IF NOT EXISTS scheduled_transfer_runs (
+	id uuid,
+	scheduled_transfer_id uuid NOT NULL,
+	scheduled_for datetime NOT NULL,
+	transfer_id uuid NOT NULL,
+	created_at datetime,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_scheduled_transfer_runs_transfer FOREIGN KEY (transfer_id) REFERENCES transfers (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduled_transfer_runs_occurrence ON scheduled_transfer_runs (scheduled_transfer_id, scheduled_for);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+	account_id uuid,
+	day date,
+	opening_balance decimal(15,2) NOT NULL,
+	closing_balance decimal(15,2) NOT NULL,
+	total_debits decimal(15,2) NOT NULL,
+	total_credits decimal(15,2) NOT NULL,
+	updated_at datetime,
+	PRIMARY KEY (account_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	type varchar(50) NOT NULL,
+	entity_id varchar(64) NOT NULL,
+	payload jsonb NOT NULL,
+	created_at datetime,
+	dispatched_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_dispatched_at ON outbox_events (dispatched_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_entity_id ON outbox_events (entity_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_tenant_id ON outbox_events (tenant_id);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	url varchar(2048) NOT NULL,
+	secret varchar(100) NOT NULL,
+	event_types jsonb NOT NULL,
+	description varchar(255),
+	active boolean NOT NULL DEFAULT true,
+	created_at datetime,
+	updated_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_tenant_id ON webhook_endpoints (tenant_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id uuid,
+	event_id uuid NOT NULL,
+	endpoint_id uuid NOT NULL,
+	status varchar(20) NOT NULL,
+	attempts integer NOT NULL DEFAULT 0,
+	next_attempt_at datetime NOT NULL,
+	last_status_code integer,
+	last_error varchar(500),
+	delivered_at datetime,
+	created_at datetime,
+	updated_at datetime,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_webhook_deliveries_event FOREIGN KEY (event_id) REFERENCES outbox_events (id),
+	CONSTRAINT fk_webhook_deliveries_endpoint FOREIGN KEY (endpoint_id) REFERENCES webhook_endpoints (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event_endpoint ON webhook_deliveries (event_id, endpoint_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id uuid,
+	tenant_id varchar(64) NOT NULL DEFAULT 'default',
+	name varchar(100) NOT NULL,
+	prefix varchar(16) NOT NULL,
+	key_hash char(64) NOT NULL,
+	scopes jsonb NOT NULL,
+	created_at datetime,
+	last_used_at datetime,
+	revoked_at datetime,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys (tenant_id);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS transactions_no_delete;
+DROP TRIGGER IF EXISTS transactions_no_update;
@@ -0,0 +1,10 @@
+-- SQLite has no row-level security, so tenants are kept apart by the
+-- repository's tenant callbacks alone.
+
+CREATE TRIGGER IF NOT EXISTS transactions_no_update
+	BEFORE UPDATE ON transactions
+	BEGIN SELECT RAISE(ABORT, 'transactions is append-only: UPDATE is not allowed'); END;
+
+CREATE TRIGGER IF NOT EXISTS transactions_no_delete
+	BEFORE DELETE ON transactions
+	BEGIN SELECT RAISE(ABORT, 'transactions is append-only: DELETE is not allowed'); END;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_account_created_at_id;
+DROP INDEX IF EXISTS idx_transactions_tenant_created_at_id;
@@ -0,0 +1,5 @@
+-- Transaction listings page newest first on (created_at, id), within a
+-- tenant or an account. These indexes let each page start where the last
+-- one ended instead of counting past the postings before it.
+CREATE INDEX IF NOT EXISTS idx_transactions_tenant_created_at_id ON transactions (tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_created_at_id ON transactions (account_id, created_at, id);
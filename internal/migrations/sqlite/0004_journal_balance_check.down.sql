@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS journal_entries_balanced;
+DROP TRIGGER IF EXISTS transactions_journal_entry_open;
@@ -0,0 +1,25 @@
+-- SQLite has no deferred triggers, so a journal entry cannot be checked once
+-- all of its postings are in, as on Postgres. Instead the repository writes
+-- the postings first, with foreign keys deferred to the commit, and the entry
+-- last; writing the entry checks that its postings balance per currency.
+-- Postings cannot join an entry that is already written, and postings whose
+-- entry is never written fail their foreign key at commit.
+--
+-- Amounts are compared in cents: SQLite reads decimal columns as floating
+-- point, whose sums need not come out at exactly zero.
+
+CREATE TRIGGER IF NOT EXISTS transactions_journal_entry_open
+	BEFORE INSERT ON transactions
+	WHEN NEW.journal_entry_id IS NOT NULL
+		AND EXISTS (SELECT 1 FROM journal_entries WHERE id = NEW.journal_entry_id)
+	BEGIN SELECT RAISE(ABORT, 'journal entry is already written: postings cannot be added to it'); END;
+
+CREATE TRIGGER IF NOT EXISTS journal_entries_balanced
+	AFTER INSERT ON journal_entries
+	WHEN EXISTS (
+		SELECT 1 FROM transactions
+		WHERE journal_entry_id = NEW.id
+		GROUP BY currency
+		HAVING SUM(CASE WHEN type = 'CREDIT' THEN 1 ELSE -1 END * CAST(ROUND(amount * 100) AS INTEGER)) <> 0
+	)
+	BEGIN SELECT RAISE(ABORT, 'journal entry is unbalanced'); END;
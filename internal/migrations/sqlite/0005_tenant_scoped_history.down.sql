@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_audit_logs_tenant_id;
+ALTER TABLE audit_logs DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS idx_account_status_changes_tenant_id;
+ALTER TABLE account_status_changes DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS idx_balance_snapshots_tenant_id;
+ALTER TABLE balance_snapshots DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS idx_scheduled_transfer_runs_tenant_id;
+ALTER TABLE scheduled_transfer_runs DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN tenant_id;
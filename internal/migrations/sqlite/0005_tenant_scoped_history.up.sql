@@ -0,0 +1,24 @@
+-- Audit logs, account status changes, balance snapshots, scheduled
+-- transfer runs and webhook deliveries belong to the tenant of the account,
+-- schedule or endpoint they record, which the existing rows take from it.
+
+ALTER TABLE audit_logs ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE audit_logs SET tenant_id = coalesce((SELECT tenant_id FROM accounts WHERE accounts.id = audit_logs.entity_id), tenant_id)
+	WHERE entity_type = 'account';
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_id ON audit_logs (tenant_id);
+
+ALTER TABLE account_status_changes ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE account_status_changes SET tenant_id = coalesce((SELECT tenant_id FROM accounts WHERE accounts.id = account_status_changes.account_id), tenant_id);
+CREATE INDEX IF NOT EXISTS idx_account_status_changes_tenant_id ON account_status_changes (tenant_id);
+
+ALTER TABLE balance_snapshots ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE balance_snapshots SET tenant_id = coalesce((SELECT tenant_id FROM accounts WHERE accounts.id = balance_snapshots.account_id), tenant_id);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_tenant_id ON balance_snapshots (tenant_id);
+
+ALTER TABLE scheduled_transfer_runs ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE scheduled_transfer_runs SET tenant_id = coalesce((SELECT tenant_id FROM scheduled_transfers WHERE scheduled_transfers.id = scheduled_transfer_runs.scheduled_transfer_id), tenant_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfer_runs_tenant_id ON scheduled_transfer_runs (tenant_id);
+
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT 'default';
+UPDATE webhook_deliveries SET tenant_id = coalesce((SELECT tenant_id FROM webhook_endpoints WHERE webhook_endpoints.id = webhook_deliveries.endpoint_id), tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant_id ON webhook_deliveries (tenant_id);
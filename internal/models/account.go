@@ -6,13 +6,31 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	SystemAccountFunding    = "system:funding"
+	SystemAccountFXPosition = "system:fx-position"
+	SystemAccountFXGainLoss = "system:fx-gain-loss"
+)
+
 type Account struct {
-	ID        string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	OwnerName string         `gorm:"type:varchar(100);not null" json:"owner_name"`
-	Balance   float64        `gorm:"type:decimal(15,2);not null;default:0" json:"balance"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             string         `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string         `gorm:"type:varchar(64);not null;default:'default';index;uniqueIndex:idx_accounts_tenant_system_owner,priority:1,where:system" json:"tenant_id"`
+	OwnerName      string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_accounts_tenant_system_owner,priority:2,where:system" json:"owner_name"`
+	Currency       string         `gorm:"type:char(3);not null;default:'USD'" json:"currency"`
+	Status         AccountStatus  `gorm:"type:varchar(20);not null;default:'active'" json:"status"`
+	Balance        Amount         `gorm:"type:decimal(15,2);not null;default:0" json:"balance"`
+	HeldBalance    Amount         `gorm:"type:decimal(15,2);not null;default:0" json:"held_balance"`
+	OverdraftLimit Amount         `gorm:"type:decimal(15,2);not null;default:0" json:"overdraft_limit"`
+	NeverNegative  bool           `gorm:"not null;default:false" json:"never_negative"`
+	System         bool           `gorm:"not null;default:false" json:"system"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// AvailableBalance is the ledger balance minus funds reserved by active holds.
+func (a *Account) AvailableBalance() Amount {
+	return a.Balance - a.HeldBalance
 }
 
 func (Account) TableName() string {
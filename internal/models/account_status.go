@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type AccountStatus string
+
+const (
+	AccountStatusActive        AccountStatus = "active"
+	AccountStatusFrozen        AccountStatus = "frozen"
+	AccountStatusDebitBlocked  AccountStatus = "debit_blocked"
+	AccountStatusCreditBlocked AccountStatus = "credit_blocked"
+	AccountStatusClosed        AccountStatus = "closed"
+)
+
+func (s AccountStatus) Valid() bool {
+	switch s {
+	case AccountStatusActive, AccountStatusFrozen, AccountStatusDebitBlocked, AccountStatusCreditBlocked, AccountStatusClosed:
+		return true
+	}
+	return false
+}
+
+func (s AccountStatus) AllowsDebit() bool {
+	return s == AccountStatusActive || s == AccountStatusCreditBlocked
+}
+
+func (s AccountStatus) AllowsCredit() bool {
+	return s == AccountStatusActive || s == AccountStatusDebitBlocked
+}
+
+type AccountStatusChange struct {
+	ID         string        `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID   string        `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	AccountID  string        `gorm:"type:uuid;not null;index" json:"account_id"`
+	FromStatus AccountStatus `gorm:"type:varchar(20);not null" json:"from_status"`
+	ToStatus   AccountStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	Reason     string        `gorm:"type:varchar(255);not null" json:"reason"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+func (AccountStatusChange) TableName() string {
+	return "account_status_changes"
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a monetary value stored as an integer number of minor units
+// (cents), matching the decimal(15,2) columns it is persisted in.
+type Amount int64
+
+const (
+	AmountScale = 2
+	MaxAmount   = Amount(999999999999999)
+
+	amountFactor = 100
+)
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrAmountPrecision = fmt.Errorf("amount has more than %d decimal places", AmountScale)
+	ErrAmountOverflow  = errors.New("amount out of range")
+)
+
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if hasFrac && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, ErrInvalidAmount
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > AmountScale {
+		return 0, ErrAmountPrecision
+	}
+	frac += strings.Repeat("0", AmountScale-len(frac))
+
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > 13 {
+		return 0, ErrAmountOverflow
+	}
+
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if Amount(units) > MaxAmount {
+		return 0, ErrAmountOverflow
+	}
+
+	if negative {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Amount) String() string {
+	sign := ""
+	units := int64(a)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/amountFactor, units%amountFactor)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return ErrInvalidAmount
+		}
+	}
+
+	parsed, err := ParseAmount(raw)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = Amount(v * amountFactor)
+		return nil
+	case float64:
+		*a = Amount(math.Round(v * amountFactor))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Amount: %w", s, err)
+	}
+	*a = parsed
+	return nil
+}
+
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"0", 0, nil},
+		{"1", 100, nil},
+		{"1.5", 150, nil},
+		{"1.50", 150, nil},
+		{"1.500", 150, nil},
+		{"0.01", 1, nil},
+		{".25", 25, nil},
+		{"007.10", 710, nil},
+		{" 12.34 ", 1234, nil},
+		{"+3.00", 300, nil},
+		{"-3.05", -305, nil},
+		{"-0.01", -1, nil},
+		{"9999999999999.99", MaxAmount, nil},
+		{"-9999999999999.99", -MaxAmount, nil},
+
+		{"1.001", 0, ErrAmountPrecision},
+		{"0.123", 0, ErrAmountPrecision},
+		{"10000000000000", 0, ErrAmountOverflow},
+		{"99999999999999.00", 0, ErrAmountOverflow},
+		{"92233720368547758.07", 0, ErrAmountOverflow},
+
+		{"", 0, ErrInvalidAmount},
+		{"-", 0, ErrInvalidAmount},
+		{".", 0, ErrInvalidAmount},
+		{"1.", 0, ErrInvalidAmount},
+		{"--1", 0, ErrInvalidAmount},
+		{"+-1", 0, ErrInvalidAmount},
+		{"1e3", 0, ErrInvalidAmount},
+		{"1,00", 0, ErrInvalidAmount},
+		{"1.2.3", 0, ErrInvalidAmount},
+		{"abc", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ParseAmount(%q) error = %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{-1, "-0.01"},
+		{150, "1.50"},
+		{-305, "-3.05"},
+		{MaxAmount, "9999999999999.99"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+		if back, err := ParseAmount(tt.want); err != nil || back != tt.in {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d", tt.want, back, err, tt.in)
+		}
+	}
+}
+
+func TestAmountScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Amount
+		wantErr bool
+	}{
+		{"nil", nil, 0, false},
+		{"string", "12.34", 1234, false},
+		{"negative string", "-0.50", -50, false},
+		{"bytes", []byte("100.00"), 10000, false},
+		{"int64 whole units", int64(42), 4200, false},
+		{"negative int64", int64(-7), -700, false},
+		{"float64", float64(12.34), 1234, false},
+		{"float64 below the cent", float64(0.29), 29, false},
+		{"float64 above the cent", float64(19.99), 1999, false},
+		{"negative float64", float64(-1.1), -110, false},
+		{"invalid string", "1.234", 0, true},
+		{"garbage", "x", 0, true},
+		{"unsupported type", true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Amount(-999)
+			err := a.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan(%#v) error = %v, want error %t", tt.src, err, tt.wantErr)
+			}
+			if !tt.wantErr && a != tt.want {
+				t.Fatalf("Scan(%#v) = %d, want %d", tt.src, a, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmountValue(t *testing.T) {
+	v, err := Amount(-1234).Value()
+	if err != nil || v != "-12.34" {
+		t.Fatalf("Value() = %v, %v, want \"-12.34\"", v, err)
+	}
+
+	var back Amount
+	if err := back.Scan(v); err != nil || back != -1234 {
+		t.Fatalf("Scan(Value()) = %d, %v, want -1234", back, err)
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	for _, a := range []Amount{0, 1, -1, 99, 100, 123456, -123456, MaxAmount, -MaxAmount} {
+		data, err := json.Marshal(a)
+		if err != nil {
+			t.Fatalf("Marshal(%d): %v", a, err)
+		}
+		var back Amount
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if back != a {
+			t.Errorf("%d round-trips through %s as %d", a, data, back)
+		}
+	}
+
+	type body struct {
+		Amount Amount `json:"amount"`
+	}
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr error
+	}{
+		{`{"amount":"10.50"}`, 1050, nil},
+		{`{"amount":10.5}`, 1050, nil},
+		{`{"amount":7}`, 700, nil},
+		{`{"amount":null}`, 0, nil},
+		{`{"amount":"0.001"}`, 0, ErrAmountPrecision},
+		{`{"amount":0.001}`, 0, ErrAmountPrecision},
+		{`{"amount":1e2}`, 0, ErrInvalidAmount},
+		{`{"amount":"ten"}`, 0, ErrInvalidAmount},
+		{`{"amount":"99999999999999"}`, 0, ErrAmountOverflow},
+	}
+	for _, tt := range tests {
+		var b body
+		err := json.Unmarshal([]byte(tt.in), &b)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Unmarshal(%s) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if b.Amount != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, b.Amount, tt.want)
+		}
+	}
+
+	data, err := json.Marshal(body{Amount: 1050})
+	if err != nil || string(data) != `{"amount":"10.50"}` {
+		t.Fatalf("Marshal = %s, %v, want {\"amount\":\"10.50\"}", data, err)
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// APIKey authenticates API callers. Only a SHA-256 hash of the key's secret
+// is stored; Prefix is the public part of the key used to look it up.
+type APIKey struct {
+	ID         string     `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID   string     `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null;uniqueIndex" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes     StringList `gorm:"type:jsonb;not null" json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	ID         string          `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID   string          `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	EntityType string          `gorm:"type:varchar(50);not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID   string          `gorm:"type:varchar(64);not null;index:idx_audit_logs_entity" json:"entity_id"`
+	Action     string          `gorm:"type:varchar(50);not null" json:"action"`
+	Changes    json.RawMessage `gorm:"type:jsonb;not null" json:"changes"`
+	Reason     string          `gorm:"type:varchar(255)" json:"reason"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// BalanceSnapshot summarises an account's postings on one UTC day. It is
+// updated with every posting, so the closing balance of an account's latest
+// snapshot always matches Account.Balance.
+type BalanceSnapshot struct {
+	AccountID      string    `gorm:"type:uuid;primaryKey" json:"account_id"`
+	TenantID       string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Day            time.Time `gorm:"type:date;primaryKey" json:"day"`
+	OpeningBalance Amount    `gorm:"type:decimal(15,2);not null" json:"opening_balance"`
+	ClosingBalance Amount    `gorm:"type:decimal(15,2);not null" json:"closing_balance"`
+	TotalDebits    Amount    `gorm:"type:decimal(15,2);not null" json:"total_debits"`
+	TotalCredits   Amount    `gorm:"type:decimal(15,2);not null" json:"total_credits"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (BalanceSnapshot) TableName() string {
+	return "balance_snapshots"
+}
+
+// StartOfDay returns midnight UTC of the day t falls on in UTC.
+func StartOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
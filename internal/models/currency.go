@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+const DefaultCurrency = "USD"
+
+// currencyMinorUnits lists the ISO 4217 currencies the ledger accepts with
+// their number of decimal places. Amounts are stored with AmountScale
+// decimals, so currencies with more minor units than that are not offered.
+var currencyMinorUnits = map[string]int{
+	"ARS": 2,
+	"AUD": 2,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CLP": 0,
+	"CNY": 2,
+	"COP": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"MXN": 2,
+	"NOK": 2,
+	"NZD": 2,
+	"PEN": 2,
+	"PLN": 2,
+	"SEK": 2,
+	"SGD": 2,
+	"USD": 2,
+	"UYU": 2,
+	"ZAR": 2,
+}
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrCurrencyPrecision   = errors.New("amount has more decimal places than the currency allows")
+)
+
+func CurrencyMinorUnits(code string) (int, error) {
+	units, ok := currencyMinorUnits[code]
+	if !ok {
+		return 0, ErrUnsupportedCurrency
+	}
+	return units, nil
+}
+
+// ValidateCurrencyAmount rejects amounts with more decimal places than the
+// currency allows, e.g. fractional yen.
+func ValidateCurrencyAmount(code string, amount Amount) error {
+	units, err := CurrencyMinorUnits(code)
+	if err != nil {
+		return err
+	}
+
+	step := Amount(1)
+	for i := units; i < AmountScale; i++ {
+		step *= 10
+	}
+	if amount%step != 0 {
+		return fmt.Errorf("%w: %s allows %d decimal places, got %s", ErrCurrencyPrecision, code, units, amount)
+	}
+	return nil
+}
+
+func SystemAccountName(kind, currency string) string {
+	return kind + ":" + currency
+}
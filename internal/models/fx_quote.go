@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type FXQuote struct {
+	ID             string     `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string     `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	FromCurrency   string     `gorm:"type:char(3);not null" json:"from_currency"`
+	ToCurrency     string     `gorm:"type:char(3);not null" json:"to_currency"`
+	MidRate        string     `gorm:"type:decimal(24,12);not null" json:"-"`
+	Rate           string     `gorm:"type:decimal(24,12);not null" json:"rate"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+	JournalEntryID *string    `gorm:"type:uuid" json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (FXQuote) TableName() string {
+	return "fx_quotes"
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusVoided   HoldStatus = "voided"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+type Hold struct {
+	ID             string     `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string     `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	AccountID      string     `gorm:"type:uuid;not null;index" json:"account_id"`
+	Amount         Amount     `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Currency       string     `gorm:"type:char(3);not null" json:"currency"`
+	CapturedAmount Amount     `gorm:"type:decimal(15,2);not null;default:0" json:"captured_amount"`
+	Status         HoldStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Description    string     `gorm:"type:varchar(255)" json:"description"`
+	ExpiresAt      time.Time  `gorm:"not null;index" json:"expires_at"`
+	JournalEntryID *string    `gorm:"type:uuid" json:"journal_entry_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (Hold) TableName() string {
+	return "holds"
+}
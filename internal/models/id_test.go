@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestIsID(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{NewID(), true},
+		{"6F9619FF-8B86-D011-B42D-00C04FC964FF", true},
+		{"", false},
+		{"not-a-uuid", false},
+		{"6f9619ff8b86d011b42d00c04fc964ff", false},
+		{"6f9619ff-8b86-d011-b42d-00c04fc964fg", false},
+		{"6f9619ff-8b86-d011-b42d+00c04fc964ff", false},
+		{"{6f9619ff-8b86-d011-b42d-00c04fc964f}", false},
+	}
+	for _, tt := range tests {
+		if got := IsID(tt.s); got != tt.want {
+			t.Errorf("IsID(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type IdempotencyKey struct {
+	TenantID     string    `gorm:"type:varchar(64);primaryKey;default:'default'"`
+	Key          string    `gorm:"type:varchar(255);primaryKey"`
+	Fingerprint  string    `gorm:"type:char(64);not null"`
+	StatusCode   int       `gorm:"not null"`
+	ResponseBody []byte    `gorm:"type:jsonb;not null"`
+	CreatedAt    time.Time `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
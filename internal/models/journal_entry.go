@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+type JournalEntryType string
+
+const (
+	JournalEntryTypeTransfer       JournalEntryType = "TRANSFER"
+	JournalEntryTypeFXTransfer     JournalEntryType = "FX_TRANSFER"
+	JournalEntryTypeOpeningBalance JournalEntryType = "OPENING_BALANCE"
+	JournalEntryTypeReversal       JournalEntryType = "REVERSAL"
+)
+
+type JournalEntry struct {
+	ID           string           `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     string           `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Type         JournalEntryType `gorm:"type:varchar(20);not null" json:"type"`
+	Description  string           `gorm:"type:varchar(255)" json:"description"`
+	ReversalOfID *string          `gorm:"type:uuid;index" json:"reversal_of,omitempty"`
+	// CreatedByKeyID is the API key that caused the entry, if any.
+	CreatedByKeyID *string   `gorm:"type:uuid;index" json:"created_by_key_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	Postings []Transaction `gorm:"foreignKey:JournalEntryID" json:"postings"`
+}
+
+func (JournalEntry) TableName() string {
+	return "journal_entries"
+}
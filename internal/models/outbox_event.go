@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventTransferCompleted   = "transfer.completed"
+	EventAccountCreated      = "account.created"
+	EventTransactionReversed = "transaction.reversed"
+)
+
+// OutboxEvent is a domain event written in the same database transaction as
+// the change it describes. DispatchedAt is set once a delivery has been
+// queued for every webhook subscribed to it.
+type OutboxEvent struct {
+	ID           string          `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID     string          `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Type         string          `gorm:"type:varchar(50);not null" json:"type"`
+	EntityID     string          `gorm:"type:varchar(64);not null;index" json:"entity_id"`
+	Payload      json.RawMessage `gorm:"type:jsonb;not null" json:"data"`
+	CreatedAt    time.Time       `json:"created_at"`
+	DispatchedAt *time.Time      `gorm:"index" json:"-"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+type ScheduleFrequency string
+
+const (
+	ScheduleFrequencyOnce    ScheduleFrequency = "once"
+	ScheduleFrequencyDaily   ScheduleFrequency = "daily"
+	ScheduleFrequencyWeekly  ScheduleFrequency = "weekly"
+	ScheduleFrequencyMonthly ScheduleFrequency = "monthly"
+	ScheduleFrequencyCron    ScheduleFrequency = "cron"
+)
+
+// CatchUpPolicy decides what happens to the runs a schedule missed while the
+// scheduler was not running.
+type CatchUpPolicy string
+
+const (
+	// CatchUpAll executes every missed run, oldest first.
+	CatchUpAll CatchUpPolicy = "all"
+	// CatchUpLatest executes only the most recent missed run.
+	CatchUpLatest CatchUpPolicy = "latest"
+	// CatchUpSkip drops missed runs and waits for the next one.
+	CatchUpSkip CatchUpPolicy = "skip"
+)
+
+func (p CatchUpPolicy) Valid() bool {
+	switch p {
+	case CatchUpAll, CatchUpLatest, CatchUpSkip:
+		return true
+	}
+	return false
+}
+
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+// ScheduledTransfer is a transfer executed once at StartAt or repeatedly
+// from StartAt on, until EndAt when it is set. All times are UTC.
+type ScheduledTransfer struct {
+	ID             string            `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string            `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	FromAccountID  string            `gorm:"type:uuid;not null;index" json:"from_account_id"`
+	ToAccountID    string            `gorm:"type:uuid;not null;index" json:"to_account_id"`
+	Amount         Amount            `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Description    string            `gorm:"type:varchar(255)" json:"description"`
+	Frequency      ScheduleFrequency `gorm:"type:varchar(20);not null" json:"frequency"`
+	DayOfMonth     int               `json:"day_of_month,omitempty"`
+	CronExpression string            `gorm:"type:varchar(100)" json:"cron,omitempty"`
+	StartAt        time.Time         `gorm:"not null" json:"start_at"`
+	EndAt          *time.Time        `json:"end_at,omitempty"`
+	CatchUp        CatchUpPolicy     `gorm:"type:varchar(20);not null" json:"catch_up"`
+	Status         ScheduleStatus    `gorm:"type:varchar(20);not null;index:idx_scheduled_transfers_due,priority:1" json:"status"`
+	NextRunAt      *time.Time        `gorm:"index:idx_scheduled_transfers_due,priority:2" json:"next_run_at,omitempty"`
+	LastRunAt      *time.Time        `json:"last_run_at,omitempty"`
+	CreatedByKeyID *string           `gorm:"type:uuid" json:"created_by_key_id,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+func (ScheduledTransfer) TableName() string {
+	return "scheduled_transfers"
+}
+
+// ScheduledTransferRun records the transfer made for one occurrence of a
+// schedule.
+type ScheduledTransferRun struct {
+	ID                  string    `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID            string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	ScheduledTransferID string    `gorm:"type:uuid;not null;uniqueIndex:idx_scheduled_transfer_runs_occurrence,priority:1" json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time `gorm:"not null;uniqueIndex:idx_scheduled_transfer_runs_occurrence,priority:2" json:"scheduled_for"`
+	TransferID          string    `gorm:"type:uuid;not null" json:"transfer_id"`
+	CreatedAt           time.Time `json:"created_at"`
+	Transfer            *Transfer `gorm:"foreignKey:TransferID" json:"transfer,omitempty"`
+}
+
+func (ScheduledTransferRun) TableName() string {
+	return "scheduled_transfer_runs"
+}
@@ -1,30 +1,94 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"ledger/internal/tenant"
 	"time"
-
-	"gorm.io/gorm"
 )
 
 type TransactionType string
 
 const (
-	TransactionTypeDebit   TransactionType = "DEBIT"
-	TransactionTypeCredit  TransactionType = "CREDIT"
-	TransactionTypeReverse TransactionType = "REVERSE"
+	TransactionTypeDebit  TransactionType = "DEBIT"
+	TransactionTypeCredit TransactionType = "CREDIT"
 )
 
+// Transaction is a single posting. Postings are append-only: each one is
+// numbered by Sequence within its account and chained to the previous one by
+// PrevHash, so any later edit or deletion breaks the account's hash chain.
 type Transaction struct {
-	ID          string          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	AccountID   string          `gorm:"type:uuid;not null;index" json:"account_id"`
-	Type        TransactionType `gorm:"type:varchar(20);not null" json:"type"`
-	Amount      float64         `gorm:"type:decimal(15,2);not null" json:"amount"`
-	Description string          `gorm:"type:varchar(255)" json:"description"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
-
-	Account Account `gorm:"foreignKey:AccountID" json:"-"`
+	ID             string          `gorm:"type:uuid;primaryKey;index:idx_transactions_tenant_created_at_id,priority:3;index:idx_transactions_account_created_at_id,priority:3" json:"id"`
+	TenantID       string          `gorm:"type:varchar(64);not null;default:'default';index;index:idx_transactions_tenant_created_at_id,priority:1" json:"tenant_id"`
+	JournalEntryID string          `gorm:"type:uuid;index" json:"journal_entry_id"`
+	AccountID      string          `gorm:"type:uuid;not null;index;index:idx_transactions_account_created_at_id,priority:1;uniqueIndex:idx_transactions_account_sequence,priority:1,where:sequence > 0" json:"account_id"`
+	Sequence       int64           `gorm:"not null;default:0;uniqueIndex:idx_transactions_account_sequence,priority:2,where:sequence > 0" json:"sequence"`
+	Type           TransactionType `gorm:"type:varchar(20);not null" json:"type"`
+	Amount         Amount          `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Currency       string          `gorm:"type:char(3);not null;default:'USD'" json:"currency"`
+	Description    string          `gorm:"type:varchar(255)" json:"description"`
+	ReversalOfID   *string         `gorm:"type:uuid;index" json:"reversal_of,omitempty"`
+	PrevHash       string          `gorm:"type:varchar(64);not null;default:''" json:"prev_hash"`
+	Hash           string          `gorm:"type:varchar(64);not null;default:''" json:"hash"`
+	CreatedByKeyID *string         `gorm:"type:uuid;index" json:"created_by_key_id,omitempty"`
+	CreatedAt      time.Time       `gorm:"index:idx_transactions_tenant_created_at_id,priority:2;index:idx_transactions_account_created_at_id,priority:2" json:"created_at"`
+
+	Account    Account  `gorm:"foreignKey:AccountID" json:"-"`
+	ReversedBy []string `gorm:"-" json:"reversed_by,omitempty"`
+}
+
+func (t Transaction) SignedAmount() Amount {
+	if t.Type == TransactionTypeDebit {
+		return -t.Amount
+	}
+	return t.Amount
+}
+
+// postingHashInput is the canonical form of a posting that its hash covers.
+// Fields added later must be omitempty so older hashes stay valid.
+type postingHashInput struct {
+	ID             string          `json:"id"`
+	JournalEntryID string          `json:"journal_entry_id"`
+	AccountID      string          `json:"account_id"`
+	Sequence       int64           `json:"sequence"`
+	Type           TransactionType `json:"type"`
+	Amount         string          `json:"amount"`
+	Currency       string          `json:"currency"`
+	Description    string          `json:"description"`
+	ReversalOfID   *string         `json:"reversal_of,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	PrevHash       string          `json:"prev_hash"`
+	CreatedByKeyID *string         `json:"created_by_key_id,omitempty"`
+	TenantID       string          `json:"tenant_id,omitempty"`
+}
+
+// ChainHash returns the hex SHA-256 of the posting's content and PrevHash.
+// CreatedAt must already be at the microsecond precision the database keeps.
+// The default tenant is left out, as it is for postings hashed before
+// tenants existed.
+func (t Transaction) ChainHash() string {
+	tenantID := t.TenantID
+	if tenantID == tenant.Default {
+		tenantID = ""
+	}
+	payload, _ := json.Marshal(postingHashInput{
+		ID:             t.ID,
+		JournalEntryID: t.JournalEntryID,
+		AccountID:      t.AccountID,
+		Sequence:       t.Sequence,
+		Type:           t.Type,
+		Amount:         t.Amount.String(),
+		Currency:       t.Currency,
+		Description:    t.Description,
+		ReversalOfID:   t.ReversalOfID,
+		CreatedAt:      t.CreatedAt.UTC().Format(time.RFC3339Nano),
+		PrevHash:       t.PrevHash,
+		CreatedByKeyID: t.CreatedByKeyID,
+		TenantID:       tenantID,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
 }
 
 func (Transaction) TableName() string {
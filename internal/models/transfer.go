@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type TransferStatus string
+
+const (
+	TransferStatusPending   TransferStatus = "pending"
+	TransferStatusCompleted TransferStatus = "completed"
+	TransferStatusFailed    TransferStatus = "failed"
+)
+
+type Transfer struct {
+	ID             string         `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string         `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	FromAccountID  string         `gorm:"type:uuid;not null;index" json:"from_account_id"`
+	ToAccountID    string         `gorm:"type:uuid;not null;index" json:"to_account_id"`
+	Amount         Amount         `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Description    string         `gorm:"type:varchar(255)" json:"description"`
+	QuoteID        *string        `gorm:"type:uuid" json:"quote_id,omitempty"`
+	Status         TransferStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	FailureCode    string         `gorm:"type:varchar(50)" json:"failure_code,omitempty"`
+	FailureReason  string         `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	JournalEntryID *string        `gorm:"type:uuid" json:"journal_entry_id,omitempty"`
+	BatchID        *string        `gorm:"type:uuid;index" json:"batch_id,omitempty"`
+	BatchIndex     int            `json:"batch_index"`
+	CreatedByKeyID *string        `gorm:"type:uuid" json:"created_by_key_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	CompletedAt    *time.Time     `json:"completed_at,omitempty"`
+}
+
+func (Transfer) TableName() string {
+	return "transfers"
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type TransferBatchStatus string
+
+const (
+	TransferBatchStatusCompleted TransferBatchStatus = "completed"
+	TransferBatchStatusFailed    TransferBatchStatus = "failed"
+)
+
+// TransferBatch groups transfers that were applied, or rejected, together.
+type TransferBatch struct {
+	ID            string              `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID      string              `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	Status        TransferBatchStatus `gorm:"type:varchar(20);not null" json:"status"`
+	ItemCount     int                 `gorm:"not null" json:"item_count"`
+	FailureReason string              `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Transfers     []Transfer          `gorm:"foreignKey:BatchID" json:"transfers"`
+}
+
+func (TransferBatch) TableName() string {
+	return "transfer_batches"
+}
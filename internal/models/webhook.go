@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	payload, err := json.Marshal([]string(l))
+	return string(payload), err
+}
+
+func (l *StringList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", src)
+	}
+}
+
+// WebhookEndpoint receives the events listed in EventTypes, or every event
+// when the list is empty.
+type WebhookEndpoint struct {
+	ID          string     `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    string     `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	URL         string     `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret      string     `gorm:"type:varchar(100);not null" json:"-"`
+	EventTypes  StringList `gorm:"type:jsonb;not null" json:"event_types"`
+	Description string     `gorm:"type:varchar(255)" json:"description"`
+	Active      bool       `gorm:"not null;default:true" json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead marks a delivery that ran out of attempts. It is
+	// only retried when redelivered by hand.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+type WebhookDelivery struct {
+	ID             string                `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       string                `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant_id"`
+	EventID        string                `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_event_endpoint,priority:1" json:"event_id"`
+	EndpointID     string                `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_event_endpoint,priority:2" json:"endpoint_id"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time             `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `gorm:"type:varchar(500)" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+
+	Event    *OutboxEvent     `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	Endpoint *WebhookEndpoint `gorm:"foreignKey:EndpointID" json:"-"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"ledger/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const hashChainBackfillBatchSize = 1000
+
+var ErrAppendOnly = errors.New("postings are append-only")
+
+// registerAppendOnlyCallbacks makes GORM refuse to update or delete
+// postings, whichever repository method tries to.
+func registerAppendOnlyCallbacks(db *gorm.DB) {
+	reject := func(tx *gorm.DB) {
+		if tx.Statement.Table == (models.Transaction{}).TableName() {
+			tx.AddError(ErrAppendOnly)
+		}
+	}
+	db.Callback().Update().Before("gorm:update").Register("ledger:append_only_update", reject)
+	db.Callback().Delete().Before("gorm:delete").Register("ledger:append_only_delete", reject)
+}
+
+// BackfillHashChain chains the postings written before postings were
+// hashed, account by account in the order they were created. It runs as
+// part of the migration that creates the append-only triggers, before it
+// does, and uses raw statements, which the append-only callbacks do not see.
+func BackfillHashChain(tx *gorm.DB) error {
+	var accountIDs []string
+	err := tx.Model(&models.Transaction{}).
+		Where("hash = ''").
+		Distinct("account_id").
+		Pluck("account_id", &accountIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, accountID := range accountIDs {
+		var head models.Transaction
+		err := tx.Where("account_id = ? AND hash <> ''", accountID).
+			Order("sequence DESC").
+			Limit(1).
+			Find(&head).Error
+		if err != nil {
+			return err
+		}
+
+		for {
+			var postings []models.Transaction
+			err := tx.Where("account_id = ? AND hash = ''", accountID).
+				Order("created_at, id").
+				Limit(hashChainBackfillBatchSize).
+				Find(&postings).Error
+			if err != nil {
+				return err
+			}
+			if len(postings) == 0 {
+				break
+			}
+
+			for _, p := range postings {
+				p.Sequence = head.Sequence + 1
+				p.PrevHash = head.Hash
+				p.Hash = p.ChainHash()
+				err := tx.Exec("UPDATE transactions SET sequence = ?, prev_hash = ?, hash = ? WHERE id = ?",
+					p.Sequence, p.PrevHash, p.Hash, p.ID).Error
+				if err != nil {
+					return err
+				}
+				head = p
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"ledger/internal/models"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// registerIDCallbacks gives records created without an ID a new UUID, so
+// IDs do not depend on the database generating them.
+func registerIDCallbacks(db *gorm.DB) {
+	db.Callback().Create().Before("gorm:create").Register("ledger:generate_id", generateID)
+}
+
+func generateID(tx *gorm.DB) {
+	if tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || field.Name != "ID" || field.FieldType.Kind() != reflect.String {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	generate := func(rv reflect.Value) {
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			if err := field.Set(ctx, rv, models.NewID()); err != nil {
+				tx.AddError(err)
+			}
+		}
+	}
+
+	switch rv := tx.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			generate(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		generate(rv)
+	}
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"ledger/internal/models"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -12,103 +16,756 @@ type LedgerRepository struct {
 }
 
 func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	registerAppendOnlyCallbacks(db)
+	registerTenantCallbacks(db)
+	registerSettingsCallbacks(db)
+	registerIDCallbacks(db)
+	registerUTCCallbacks(db)
+
 	return &LedgerRepository{
 		db: db,
 	}
 }
 
-func (r *LedgerRepository) CreateAccount(ownerName string, initialBalance float64) (*models.Account, error) {
-	account := &models.Account{
-		OwnerName: ownerName,
-		Balance:   initialBalance,
-	}
+// ReadSnapshot calls fn with a repository whose reads all see the same
+// consistent snapshot of the database.
+func (r *LedgerRepository) ReadSnapshot(fn func(store LedgerStore) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := setTenantSetting(tx); err != nil {
+			return err
+		}
+		return fn(&LedgerRepository{db: tx})
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+func (r *LedgerRepository) CreateAccountInTx(tx Tx, account *models.Account) error {
+	return gormTx(tx).Create(account).Error
+}
 
-	if err := r.db.Create(account).Error; err != nil {
+func (r *LedgerRepository) GetAccountByID(id string) (*models.Account, error) {
+	var account models.Account
+	if err := r.db.First(&account, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
+	return &account, nil
+}
 
-	return account, nil
+func (r *LedgerRepository) GetAccountByIDForUpdate(tx Tx, id string) (*models.Account, error) {
+	var account models.Account
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
 }
 
-func (r *LedgerRepository) GetAccountByID(id string) (*models.Account, error) {
+func (r *LedgerRepository) GetSystemAccountForUpdate(tx Tx, kind, currency string) (*models.Account, error) {
+	name := models.SystemAccountName(kind, currency)
+
 	var account models.Account
-	if err := r.db.First(&account, "id = ?", id).Error; err != nil {
+	err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "system = ? AND owner_name = ?", true, name).Error
+	if err == nil {
+		return &account, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	system := &models.Account{
+		OwnerName: name,
+		Currency:  currency,
+		System:    true,
+	}
+	if err := gormTx(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(system).Error; err != nil {
+		return nil, err
+	}
+
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "system = ? AND owner_name = ?", true, name).Error; err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-func (r *LedgerRepository) GetAccountByIDForUpdate(tx *gorm.DB, id string) (*models.Account, error) {
+// GetSystemAccount is GetSystemAccountForUpdate without the row lock, for
+// callers that lock the account later together with others.
+func (r *LedgerRepository) GetSystemAccount(tx Tx, kind, currency string) (*models.Account, error) {
+	name := models.SystemAccountName(kind, currency)
+
 	var account models.Account
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "id = ?", id).Error; err != nil {
+	err := gormTx(tx).First(&account, "system = ? AND owner_name = ?", true, name).Error
+	if err == nil {
+		return &account, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	system := &models.Account{
+		OwnerName: name,
+		Currency:  currency,
+		System:    true,
+	}
+	if err := gormTx(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(system).Error; err != nil {
+		return nil, err
+	}
+
+	if err := gormTx(tx).First(&account, "system = ? AND owner_name = ?", true, name).Error; err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-func (r *LedgerRepository) UpdateAccountBalance(id string, newBalance float64) error {
-	return r.db.Model(&models.Account{}).Where("id = ?", id).Update("balance", newBalance).Error
+func (r *LedgerRepository) UpdateAccountBalanceInTx(tx Tx, id string, newBalance models.Amount) error {
+	return gormTx(tx).Model(&models.Account{}).Where("id = ?", id).Update("balance", newBalance).Error
+}
+
+func (r *LedgerRepository) UpdateAccountHeldBalanceInTx(tx Tx, id string, heldBalance models.Amount) error {
+	return gormTx(tx).Model(&models.Account{}).Where("id = ?", id).Update("held_balance", heldBalance).Error
+}
+
+// CreateJournalEntryInTx writes the entry and its postings. On SQLite the
+// postings go first, with foreign keys deferred to the commit, because
+// writing the entry is what checks that they balance there.
+func (r *LedgerRepository) CreateJournalEntryInTx(tx Tx, entry *models.JournalEntry) error {
+	db := gormTx(tx)
+	if isPostgres(db) {
+		return db.Create(entry).Error
+	}
+
+	if err := db.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+		return err
+	}
+	if entry.ID == "" {
+		entry.ID = models.NewID()
+	}
+	if len(entry.Postings) > 0 {
+		for i := range entry.Postings {
+			entry.Postings[i].JournalEntryID = entry.ID
+		}
+		if err := db.Create(&entry.Postings).Error; err != nil {
+			return err
+		}
+	}
+	return db.Omit(clause.Associations).Create(entry).Error
+}
+
+func (r *LedgerRepository) GetJournalEntryByID(id string) (*models.JournalEntry, error) {
+	return r.GetJournalEntryByIDInTx(r.db, id)
+}
+
+func (r *LedgerRepository) GetJournalEntryByIDInTx(tx Tx, id string) (*models.JournalEntry, error) {
+	var entry models.JournalEntry
+	err := gormTx(tx).Preload("Postings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).First(&entry, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetTransactions returns up to limit of the postings matching filter,
+// newest first. With filter.Before set they are the ones just newer than
+// it, still newest first.
+func (r *LedgerRepository) GetTransactions(filter TransactionFilter, limit int) ([]models.Transaction, error) {
+	query := r.db
+	if filter.AccountID != "" {
+		query = query.Where("account_id = ?", filter.AccountID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To.UTC())
+	}
+	if filter.After != nil {
+		query = query.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt.UTC(), filter.After.ID)
+	}
+	if filter.Before != nil {
+		query = query.Where("(created_at, id) > (?, ?)", filter.Before.CreatedAt.UTC(), filter.Before.ID)
+	}
+
+	order := "created_at desc, id desc"
+	if filter.Before != nil {
+		order = "created_at, id"
+	}
+
+	var transactions []models.Transaction
+	if err := query.Order(order).Limit(limit).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	if filter.Before != nil {
+		slices.Reverse(transactions)
+	}
+	return transactions, nil
+}
+
+func (r *LedgerRepository) GetTransactionByID(id string) (*models.Transaction, error) {
+	return r.GetTransactionByIDInTx(r.db, id)
 }
 
-func (r *LedgerRepository) UpdateAccountBalanceInTx(tx *gorm.DB, id string, newBalance float64) error {
-	return tx.Model(&models.Account{}).Where("id = ?", id).Update("balance", newBalance).Error
+func (r *LedgerRepository) GetTransactionByIDInTx(tx Tx, id string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := gormTx(tx).First(&transaction, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
 }
 
-func (r *LedgerRepository) CreateTransaction(accountID string, txType models.TransactionType, amount float64, description string) (*models.Transaction, error) {
-	tx := &models.Transaction{
-		AccountID:   accountID,
-		Type:        txType,
-		Amount:      amount,
-		Description: description,
+func (r *LedgerRepository) GetReversedAmountsInTx(tx Tx, transactionIDs []string) (map[string]models.Amount, error) {
+	var rows []struct {
+		ReversalOfID string
+		Total        models.Amount
+	}
+	err := gormTx(tx).Model(&models.Transaction{}).
+		Select("reversal_of_id, SUM(amount) AS total").
+		Where("reversal_of_id IN ?", transactionIDs).
+		Group("reversal_of_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := make(map[string]models.Amount, len(rows))
+	for _, row := range rows {
+		amounts[row.ReversalOfID] = row.Total
 	}
+	return amounts, nil
+}
 
-	if err := r.db.Create(tx).Error; err != nil {
+func (r *LedgerRepository) GetReversalsOf(transactionIDs []string) ([]models.Transaction, error) {
+	var reversals []models.Transaction
+	err := r.db.Where("reversal_of_id IN ?", transactionIDs).
+		Order("created_at, id").
+		Find(&reversals).Error
+
+	return reversals, err
+}
+
+func (r *LedgerRepository) GetIdempotencyKey(key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.First(&record, "key = ?", key).Error; err != nil {
 		return nil, err
 	}
+	return &record, nil
+}
+
+// SaveIdempotencyKeyInTx stores record unless a live record with the same key
+// already exists, in which case it reports false.
+func (r *LedgerRepository) SaveIdempotencyKeyInTx(tx Tx, record *models.IdempotencyKey) (bool, error) {
+	result := gormTx(tx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status_code", "response_body", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{record.CreatedAt.UTC()}},
+		}},
+	}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *LedgerRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now.UTC()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *LedgerRepository) CreateFXQuote(quote *models.FXQuote) error {
+	return r.db.Create(quote).Error
+}
+
+func (r *LedgerRepository) GetFXQuoteByIDForUpdate(tx Tx, id string) (*models.FXQuote, error) {
+	var quote models.FXQuote
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *LedgerRepository) MarkFXQuoteUsedInTx(tx Tx, id, journalEntryID string, usedAt time.Time) error {
+	return gormTx(tx).Model(&models.FXQuote{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"used_at":          usedAt,
+		"journal_entry_id": journalEntryID,
+	}).Error
+}
 
-	return tx, nil
+func (r *LedgerRepository) CreateHoldInTx(tx Tx, hold *models.Hold) error {
+	return gormTx(tx).Create(hold).Error
 }
 
-func (r *LedgerRepository) CreateTransactionInTx(tx *gorm.DB, accountID string, txType models.TransactionType, amount float64, description string) (*models.Transaction, error) {
-	transaction := &models.Transaction{
-		AccountID:   accountID,
-		Type:        txType,
-		Amount:      amount,
-		Description: description,
+func (r *LedgerRepository) GetHoldByID(id string) (*models.Hold, error) {
+	var hold models.Hold
+	if err := r.db.First(&hold, "id = ?", id).Error; err != nil {
+		return nil, err
 	}
+	return &hold, nil
+}
 
-	if err := tx.Create(transaction).Error; err != nil {
+func (r *LedgerRepository) GetHoldByIDForUpdate(tx Tx, id string) (*models.Hold, error) {
+	var hold models.Hold
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
+	return &hold, nil
+}
 
-	return transaction, nil
+func (r *LedgerRepository) UpdateHoldInTx(tx Tx, hold *models.Hold) error {
+	return gormTx(tx).Save(hold).Error
 }
 
-func (r *LedgerRepository) GetTransactionsByAccountID(accountID string, limit, offset int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
+func (r *LedgerRepository) GetExpiredHolds(now time.Time, limit int) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("status = ? AND expires_at <= ?", models.HoldStatusActive, now.UTC()).
+		Order("expires_at").
+		Limit(limit).
+		Find(&holds).Error
+
+	return holds, err
+}
+
+func (r *LedgerRepository) UpdateAccountPolicyInTx(tx Tx, id string, overdraftLimit models.Amount, neverNegative bool) error {
+	return gormTx(tx).Model(&models.Account{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"overdraft_limit": overdraftLimit,
+		"never_negative":  neverNegative,
+	}).Error
+}
+
+func (r *LedgerRepository) CreateAuditLogInTx(tx Tx, entry *models.AuditLog) error {
+	return gormTx(tx).Create(entry).Error
+}
+
+func (r *LedgerRepository) GetAuditLogs(entityType, entityID string) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at").
+		Find(&logs).Error
+
+	return logs, err
+}
+
+func (r *LedgerRepository) UpdateAccountStatusInTx(tx Tx, id string, status models.AccountStatus) error {
+	return gormTx(tx).Model(&models.Account{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (r *LedgerRepository) CreateAccountStatusChangeInTx(tx Tx, change *models.AccountStatusChange) error {
+	return gormTx(tx).Create(change).Error
+}
+
+func (r *LedgerRepository) GetAccountStatusChanges(accountID string) ([]models.AccountStatusChange, error) {
+	var changes []models.AccountStatusChange
 	err := r.db.Where("account_id = ?", accountID).
-		Order("created_at desc").
+		Order("created_at").
+		Find(&changes).Error
+
+	return changes, err
+}
+
+func (r *LedgerRepository) UpdateIdempotencyResponseInTx(tx Tx, key string, statusCode int, body []byte) error {
+	return gormTx(tx).Model(&models.IdempotencyKey{}).Where("key = ?", key).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"response_body": body,
+	}).Error
+}
+
+func (r *LedgerRepository) CreateTransferInTx(tx Tx, transfer *models.Transfer) error {
+	return gormTx(tx).Create(transfer).Error
+}
+
+func (r *LedgerRepository) GetTransferByID(id string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := r.db.First(&transfer, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *LedgerRepository) GetTransferByIDForUpdate(tx Tx, id string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *LedgerRepository) UpdateTransferInTx(tx Tx, transfer *models.Transfer) error {
+	return gormTx(tx).Save(transfer).Error
+}
+
+func (r *LedgerRepository) GetPendingTransferIDs(limit int) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.Transfer{}).
+		Where("status = ?", models.TransferStatusPending).
+		Order("created_at").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	return ids, err
+}
+
+func (r *LedgerRepository) CreateTransferBatchInTx(tx Tx, batch *models.TransferBatch) error {
+	return gormTx(tx).Create(batch).Error
+}
+
+func (r *LedgerRepository) GetTransferBatchByID(id string) (*models.TransferBatch, error) {
+	var batch models.TransferBatch
+	err := r.db.Preload("Transfers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("batch_index")
+	}).First(&batch, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *LedgerRepository) CreateScheduledTransferInTx(tx Tx, schedule *models.ScheduledTransfer) error {
+	return gormTx(tx).Create(schedule).Error
+}
+
+func (r *LedgerRepository) GetScheduledTransferByID(id string) (*models.ScheduledTransfer, error) {
+	var schedule models.ScheduledTransfer
+	if err := r.db.First(&schedule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *LedgerRepository) GetScheduledTransferByIDForUpdate(tx Tx, id string) (*models.ScheduledTransfer, error) {
+	var schedule models.ScheduledTransfer
+	if err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ClaimDueScheduledTransferInTx locks the active schedule that has been due
+// the longest, skipping schedules another transaction already holds.
+func (r *LedgerRepository) ClaimDueScheduledTransferInTx(tx Tx, now time.Time) (*models.ScheduledTransfer, error) {
+	var schedule models.ScheduledTransfer
+	err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_run_at <= ?", models.ScheduleStatusActive, now.UTC()).
+		Order("next_run_at").
+		First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *LedgerRepository) UpdateScheduledTransferInTx(tx Tx, schedule *models.ScheduledTransfer) error {
+	return gormTx(tx).Save(schedule).Error
+}
+
+func (r *LedgerRepository) CreateScheduledTransferRunInTx(tx Tx, run *models.ScheduledTransferRun) error {
+	return gormTx(tx).Create(run).Error
+}
+
+func (r *LedgerRepository) GetScheduledTransferRuns(scheduleID string, limit, offset int) ([]models.ScheduledTransferRun, error) {
+	var runs []models.ScheduledTransferRun
+	err := r.db.Preload("Transfer").
+		Where("scheduled_transfer_id = ?", scheduleID).
+		Order("scheduled_for DESC").
 		Limit(limit).
 		Offset(offset).
+		Find(&runs).Error
+
+	return runs, err
+}
+
+// UpsertBalanceSnapshotInTx adds snapshot's debits and credits to the
+// account's snapshot for the day, creating it with snapshot's opening
+// balance when it is the first posting of the day.
+func (r *LedgerRepository) UpsertBalanceSnapshotInTx(tx Tx, snapshot *models.BalanceSnapshot) error {
+	return gormTx(tx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "account_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"closing_balance": gorm.Expr("EXCLUDED.closing_balance"),
+			"total_debits":    gorm.Expr("balance_snapshots.total_debits + EXCLUDED.total_debits"),
+			"total_credits":   gorm.Expr("balance_snapshots.total_credits + EXCLUDED.total_credits"),
+			"updated_at":      gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(snapshot).Error
+}
+
+// GetLastBalanceSnapshotBefore returns the account's latest snapshot for a
+// day before day, or nil when the account had no postings before it.
+func (r *LedgerRepository) GetLastBalanceSnapshotBefore(accountID string, day time.Time) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	err := r.db.Where("account_id = ? AND day < ?", accountID, day.UTC()).
+		Order("day DESC").
+		First(&snapshot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *LedgerRepository) GetBalanceSnapshots(accountID string, from, to time.Time) ([]models.BalanceSnapshot, error) {
+	var snapshots []models.BalanceSnapshot
+	err := r.db.Where("account_id = ? AND day >= ? AND day <= ?", accountID, from.UTC(), to.UTC()).
+		Order("day").
+		Find(&snapshots).Error
+
+	return snapshots, err
+}
+
+// SumPostings returns the net effect on the account's balance of the
+// postings created between from and to, inclusive. It reads through Find
+// rather than Row so that the row-level security settings are applied.
+func (r *LedgerRepository) SumPostings(accountID string, from, to time.Time) (models.Amount, error) {
+	var sum struct {
+		Total models.Amount
+	}
+	err := r.db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE -amount END), 0) AS total", models.TransactionTypeCredit).
+		Where("account_id = ? AND created_at >= ? AND created_at <= ?", accountID, from.UTC(), to.UTC()).
+		Find(&sum).Error
+
+	return sum.Total, err
+}
+
+// GetPostingsBetween returns up to limit of the account's postings created
+// in [from, to), oldest first.
+func (r *LedgerRepository) GetPostingsBetween(accountID string, from, to time.Time, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Where("account_id = ? AND created_at >= ? AND created_at < ?", accountID, from.UTC(), to.UTC()).
+		Order("created_at, id").
+		Limit(limit).
 		Find(&transactions).Error
 
 	return transactions, err
 }
 
-func (r *LedgerRepository) GetAllTransactions(limit, offset int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
-	err := r.db.Order("created_at desc").
+// GetChainHeadInTx returns the account's latest posting, or nil when it has
+// none yet.
+func (r *LedgerRepository) GetChainHeadInTx(tx Tx, accountID string) (*models.Transaction, error) {
+	var head models.Transaction
+	err := gormTx(tx).Select("id", "sequence", "hash").
+		Where("account_id = ?", accountID).
+		Order("sequence DESC").
+		Take(&head).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &head, nil
+}
+
+// GetAccountsForVerification returns every account, deleted ones included,
+// in ID order.
+func (r *LedgerRepository) GetAccountsForVerification() ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Unscoped().Order("id").Find(&accounts).Error
+	return accounts, err
+}
+
+// GetAccounts returns a page of the customer accounts, oldest first. System
+// accounts are left out.
+func (r *LedgerRepository) GetAccounts(limit, offset int) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Where("system = ?", false).
+		Order("created_at, id").
 		Limit(limit).
 		Offset(offset).
+		Find(&accounts).Error
+	return accounts, err
+}
+
+// GetPostingsAfterSequence returns up to limit of the account's postings
+// after sequence, in chain order.
+func (r *LedgerRepository) GetPostingsAfterSequence(accountID string, sequence int64, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.Where("account_id = ? AND sequence > ?", accountID, sequence).
+		Order("sequence").
+		Limit(limit).
 		Find(&transactions).Error
 
 	return transactions, err
 }
 
-func (r *LedgerRepository) GetTransactionByID(id string) (*models.Transaction, error) {
-	var transaction models.Transaction
-	if err := r.db.First(&transaction, "id = ?", id).Error; err != nil {
+func (r *LedgerRepository) CreateOutboxEventInTx(tx Tx, event *models.OutboxEvent) error {
+	return gormTx(tx).Create(event).Error
+}
+
+// ClaimUndispatchedEventsInTx locks up to limit events that have not been
+// fanned out to webhooks yet, skipping those another dispatcher holds.
+func (r *LedgerRepository) ClaimUndispatchedEventsInTx(tx Tx, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := gormTx(tx).Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("dispatched_at IS NULL").
+		Order("created_at").
+		Limit(limit).
+		Find(&events).Error
+
+	return events, err
+}
+
+func (r *LedgerRepository) MarkEventsDispatchedInTx(tx Tx, ids []string, at time.Time) error {
+	return gormTx(tx).Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("dispatched_at", at).Error
+}
+
+func (r *LedgerRepository) GetActiveWebhookEndpointsInTx(tx Tx) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := gormTx(tx).Where("active").Find(&endpoints).Error
+	return endpoints, err
+}
+
+func (r *LedgerRepository) CreateWebhookDeliveriesInTx(tx Tx, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return gormTx(tx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+// ClaimDueWebhookDeliveries leases up to limit due deliveries by pushing
+// their next attempt past lease, so no other dispatcher picks them up while
+// they are being sent.
+func (r *LedgerRepository) ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now.UTC()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", deliveryIDs(deliveries)).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(deliveries) == 0 {
 		return nil, err
 	}
-	return &transaction, nil
+
+	var claimed []models.WebhookDelivery
+	err = r.db.Preload("Event").Preload("Endpoint").
+		Where("id IN ?", deliveryIDs(deliveries)).
+		Find(&claimed).Error
+	return claimed, err
+}
+
+func deliveryIDs(deliveries []models.WebhookDelivery) []string {
+	ids := make([]string, len(deliveries))
+	for i, d := range deliveries {
+		ids[i] = d.ID
+	}
+	return ids
+}
+
+// UpdateWebhookDeliveryAttempt stores the outcome of an attempt to send
+// delivery.
+func (r *LedgerRepository) UpdateWebhookDeliveryAttempt(delivery *models.WebhookDelivery) error {
+	return r.db.Model(&models.WebhookDelivery{ID: delivery.ID}).Updates(map[string]interface{}{
+		"status":           delivery.Status,
+		"attempts":         delivery.Attempts,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+		"delivered_at":     delivery.DeliveredAt,
+		"updated_at":       r.db.NowFunc(),
+	}).Error
+}
+
+func (r *LedgerRepository) GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.Preload("Event").First(&delivery, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *LedgerRepository) GetWebhookDeliveries(endpointID string, status models.WebhookDeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.db.Preload("Event").Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+
+	return deliveries, err
+}
+
+func (r *LedgerRepository) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *LedgerRepository) GetWebhookEndpoints() ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.db.Order("created_at").Find(&endpoints).Error
+	return endpoints, err
+}
+
+func (r *LedgerRepository) GetWebhookEndpointByID(id string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := r.db.First(&endpoint, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *LedgerRepository) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.db.Save(endpoint).Error
+}
+
+// DeleteWebhookEndpoint removes the endpoint together with its deliveries.
+func (r *LedgerRepository) DeleteWebhookEndpoint(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.WebhookEndpoint{}, "id = ?", id).Error
+	})
+}
+
+func (r *LedgerRepository) CreateAPIKey(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *LedgerRepository) GetAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Order("created_at").Find(&keys).Error
+	return keys, err
+}
+
+func (r *LedgerRepository) GetAPIKeyByID(id string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *LedgerRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, "prefix = ?", prefix).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey marks the key revoked, keeping the time of an earlier
+// revocation.
+func (r *LedgerRepository) RevokeAPIKey(id string, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *LedgerRepository) TouchAPIKey(id string, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
 }
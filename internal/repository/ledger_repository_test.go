@@ -0,0 +1,165 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/repository/storetest"
+	"ledger/internal/tenant"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestLedgerRepositorySQLite(t *testing.T) {
+	storetest.Run(t, storetest.NewSQLiteStore)
+}
+
+func TestLedgerRepositoryPostgres(t *testing.T) {
+	storetest.Run(t, storetest.NewPostgresStore)
+}
+
+// On Postgres the row-level security policies fail closed: statements that
+// carry no tenant, even ones that go around the repository's tenant
+// callbacks, see and write nothing unless they are marked as spanning every
+// tenant.
+func TestPostgresRowLevelSecurityFailsClosed(t *testing.T) {
+	db := storetest.OpenPostgres(t)
+	store := repository.NewLedgerRepository(db)
+	account := storetest.CreateAccount(t, storetest.InTenant(store, models.NewID()), 100)
+
+	if _, err := store.GetAccountByID(account.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetAccountByID without a tenant = %v, want ErrNotFound", err)
+	}
+	if _, err := storetest.InTenant(store, models.NewID()).GetAccountByID(account.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetAccountByID from another tenant = %v, want ErrNotFound", err)
+	}
+
+	var count int64
+	if err := db.Table("accounts").Where("id = ?", account.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("counted %d accounts without a tenant, want 0", count)
+	}
+	err := store.Transaction(func(tx repository.Tx) error {
+		return store.UpdateAccountBalanceInTx(tx, account.ID, 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Transaction(func(tx repository.Tx) error {
+		return store.CreateAccountInTx(tx, &models.Account{OwnerName: "test", Currency: "USD"})
+	})
+	if err == nil {
+		t.Error("CreateAccountInTx without a tenant succeeded, want the policy to refuse it")
+	}
+
+	// Sums run outside a transaction too, and see the tenant's postings.
+	scoped := storetest.InTenant(store, account.TenantID)
+	other := storetest.CreateAccount(t, scoped, 0)
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	storetest.Transfer(t, scoped, other.ID, account.ID, 250, at)
+	if total, err := scoped.SumPostings(account.ID, at.Add(-time.Second), at.Add(time.Second)); err != nil || total != 250 {
+		t.Errorf("SumPostings in the tenant = %s, %v, want 2.50", total, err)
+	}
+	if total, err := store.SumPostings(account.ID, at.Add(-time.Second), at.Add(time.Second)); err != nil || total != 0 {
+		t.Errorf("SumPostings without a tenant = %s, %v, want nothing", total, err)
+	}
+
+	bypass := store.WithContext(tenant.WithBypass(context.Background()))
+	got, err := bypass.GetAccountByID(account.ID)
+	if err != nil {
+		t.Fatalf("GetAccountByID with the bypass: %v", err)
+	}
+	if got.Balance != 100 {
+		t.Errorf("balance = %s after an update without a tenant, want it untouched at 1.00", got.Balance)
+	}
+}
+
+// The triggers that keep journal entries balanced on SQLite hold against
+// statements that go around the repository.
+func TestSQLiteJournalBalanceTriggers(t *testing.T) {
+	db := storetest.OpenSQLite(t)
+	store := repository.NewLedgerRepository(db)
+	from := storetest.CreateAccount(t, store, 0)
+	to := storetest.CreateAccount(t, store, 0)
+	entry := storetest.Transfer(t, store, from.ID, to.ID, 100, time.Now())
+
+	insertPosting := func(tx *gorm.DB, entryID string, postingType models.TransactionType, amount string) error {
+		return tx.Exec(`INSERT INTO transactions (id, journal_entry_id, account_id, type, amount, currency, created_at)
+			VALUES (?, ?, ?, ?, ?, 'USD', ?)`, models.NewID(), entryID, from.ID, postingType, amount, time.Now().UTC()).Error
+	}
+	insertEntry := func(tx *gorm.DB, entryID string) error {
+		return tx.Exec(`INSERT INTO journal_entries (id, type, created_at) VALUES (?, 'transfer', ?)`, entryID, time.Now().UTC()).Error
+	}
+
+	t.Run("posting added to a written entry", func(t *testing.T) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return insertPosting(tx, entry.ID, models.TransactionTypeCredit, "1.00")
+		})
+		if err == nil || !strings.Contains(err.Error(), "already written") {
+			t.Fatalf("insert = %v, want the entry to be closed", err)
+		}
+	})
+
+	t.Run("entry written after unbalanced postings", func(t *testing.T) {
+		id := models.NewID()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+				return err
+			}
+			if err := insertPosting(tx, id, models.TransactionTypeDebit, "0.10"); err != nil {
+				return err
+			}
+			if err := insertPosting(tx, id, models.TransactionTypeCredit, "0.20"); err != nil {
+				return err
+			}
+			return insertEntry(tx, id)
+		})
+		if err == nil || !strings.Contains(err.Error(), "unbalanced") {
+			t.Fatalf("insert = %v, want the entry to be refused as unbalanced", err)
+		}
+	})
+
+	// Amounts that do not add up to zero in floating point still balance.
+	t.Run("entry written after balanced postings", func(t *testing.T) {
+		id := models.NewID()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+				return err
+			}
+			for _, posting := range []struct {
+				postingType models.TransactionType
+				amount      string
+			}{
+				{models.TransactionTypeCredit, "0.10"},
+				{models.TransactionTypeCredit, "0.20"},
+				{models.TransactionTypeDebit, "0.30"},
+			} {
+				if err := insertPosting(tx, id, posting.postingType, posting.amount); err != nil {
+					return err
+				}
+			}
+			return insertEntry(tx, id)
+		})
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	})
+
+	t.Run("postings whose entry is never written", func(t *testing.T) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+				return err
+			}
+			return insertPosting(tx, models.NewID(), models.TransactionTypeCredit, "1.00")
+		})
+		if err == nil {
+			t.Fatal("a posting without its journal entry was committed")
+		}
+	})
+}
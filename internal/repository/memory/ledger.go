@@ -0,0 +1,922 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"slices"
+	"time"
+)
+
+func (s *Store) CreateAccountInTx(tx repository.Tx, account *models.Account) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, account)
+}
+
+func (s *Store) GetAccountByID(id string) (*models.Account, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.Account](r, id)
+}
+
+func (s *Store) GetAccountByIDForUpdate(tx repository.Tx, id string) (*models.Account, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byIDForUpdate[models.Account](r, id)
+}
+
+func (s *Store) GetSystemAccountForUpdate(tx repository.Tx, kind, currency string) (*models.Account, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	name := models.SystemAccountName(kind, currency)
+
+	// The lock on the name stands in for the unique index, so that
+	// concurrent transactions create the account only once.
+	if err := r.lock("accounts/system/" + r.tenant + "/" + name); err != nil {
+		return nil, err
+	}
+	accounts := all(r, func(a *models.Account) bool {
+		return a.System && a.OwnerName == name
+	})
+	if len(accounts) > 0 {
+		return byIDForUpdate[models.Account](r, accounts[0].ID)
+	}
+
+	system := &models.Account{
+		OwnerName: name,
+		Currency:  currency,
+		System:    true,
+	}
+	if err := insert(r, system); err != nil {
+		return nil, err
+	}
+	return system, nil
+}
+
+func (s *Store) GetSystemAccount(tx repository.Tx, kind, currency string) (*models.Account, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	name := models.SystemAccountName(kind, currency)
+	if err := r.lock("accounts/system/" + r.tenant + "/" + name); err != nil {
+		return nil, err
+	}
+	accounts := all(r, func(a *models.Account) bool {
+		return a.System && a.OwnerName == name
+	})
+	if len(accounts) > 0 {
+		return &accounts[0], nil
+	}
+
+	system := &models.Account{
+		OwnerName: name,
+		Currency:  currency,
+		System:    true,
+	}
+	if err := insert(r, system); err != nil {
+		return nil, err
+	}
+	return system, nil
+}
+
+func (s *Store) GetAccountsForVerification() ([]models.Account, error) {
+	r := s.read()
+	defer r.unlock()
+
+	accounts := all[models.Account](r, nil)
+	sortBy(accounts, func(a, b *models.Account) bool { return a.ID < b.ID })
+	return accounts, nil
+}
+
+func (s *Store) GetAccounts(limit, offset int) ([]models.Account, error) {
+	r := s.read()
+	defer r.unlock()
+
+	accounts := all(r, func(a *models.Account) bool { return !a.System })
+	sortBy(accounts, func(a, b *models.Account) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return page(accounts, limit, offset), nil
+}
+
+func (s *Store) updateAccount(tx repository.Tx, id string, change func(*models.Account)) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	_, err := update(r, func(a *models.Account) bool { return a.ID == id }, change)
+	return err
+}
+
+func (s *Store) UpdateAccountBalanceInTx(tx repository.Tx, id string, newBalance models.Amount) error {
+	return s.updateAccount(tx, id, func(a *models.Account) {
+		a.Balance = newBalance
+	})
+}
+
+func (s *Store) UpdateAccountHeldBalanceInTx(tx repository.Tx, id string, heldBalance models.Amount) error {
+	return s.updateAccount(tx, id, func(a *models.Account) {
+		a.HeldBalance = heldBalance
+	})
+}
+
+func (s *Store) UpdateAccountPolicyInTx(tx repository.Tx, id string, overdraftLimit models.Amount, neverNegative bool) error {
+	return s.updateAccount(tx, id, func(a *models.Account) {
+		a.OverdraftLimit = overdraftLimit
+		a.NeverNegative = neverNegative
+	})
+}
+
+func (s *Store) UpdateAccountStatusInTx(tx repository.Tx, id string, status models.AccountStatus) error {
+	return s.updateAccount(tx, id, func(a *models.Account) {
+		a.Status = status
+	})
+}
+
+func (s *Store) CreateAccountStatusChangeInTx(tx repository.Tx, change *models.AccountStatusChange) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, change)
+}
+
+func (s *Store) GetAccountStatusChanges(accountID string) ([]models.AccountStatusChange, error) {
+	r := s.read()
+	defer r.unlock()
+
+	changes := all(r, func(c *models.AccountStatusChange) bool { return c.AccountID == accountID })
+	sortBy(changes, func(a, b *models.AccountStatusChange) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return changes, nil
+}
+
+func (s *Store) CreateAuditLogInTx(tx repository.Tx, entry *models.AuditLog) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, entry)
+}
+
+func (s *Store) GetAuditLogs(entityType, entityID string) ([]models.AuditLog, error) {
+	r := s.read()
+	defer r.unlock()
+
+	logs := all(r, func(l *models.AuditLog) bool {
+		return l.EntityType == entityType && l.EntityID == entityID
+	})
+	sortBy(logs, func(a, b *models.AuditLog) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return logs, nil
+}
+
+// CreateJournalEntryInTx writes the entry and its postings, refusing an
+// entry that does not balance per currency like the deferred constraint
+// trigger on Postgres does.
+func (s *Store) CreateJournalEntryInTx(tx repository.Tx, entry *models.JournalEntry) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	if err := insert(r, entry); err != nil {
+		return err
+	}
+
+	totals := make(map[string]models.Amount)
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		posting.JournalEntryID = entry.ID
+		if err := insert(r, posting); err != nil {
+			return err
+		}
+		totals[posting.Currency] += posting.SignedAmount()
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("journal entry %s is unbalanced by %s %s", entry.ID, total, currency)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetJournalEntryByID(id string) (*models.JournalEntry, error) {
+	r := s.read()
+	defer r.unlock()
+	return journalEntry(r, id)
+}
+
+func (s *Store) GetJournalEntryByIDInTx(tx repository.Tx, id string) (*models.JournalEntry, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return journalEntry(r, id)
+}
+
+func journalEntry(r read, id string) (*models.JournalEntry, error) {
+	entry, err := byID[models.JournalEntry](r, id)
+	if err != nil {
+		return nil, err
+	}
+	entry.Postings = all(r, func(t *models.Transaction) bool { return t.JournalEntryID == id })
+	sortBy(entry.Postings, createdFirst)
+	return entry, nil
+}
+
+// createdFirst orders postings by creation time, then ID.
+func createdFirst(a, b *models.Transaction) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// newestFirst orders postings by creation time, then ID, both descending.
+func newestFirst(a, b *models.Transaction) bool {
+	return createdFirst(b, a)
+}
+
+func (s *Store) GetTransactions(filter repository.TransactionFilter, limit int) ([]models.Transaction, error) {
+	r := s.read()
+	defer r.unlock()
+
+	after, before := atCursor(filter.After), atCursor(filter.Before)
+	transactions := all(r, func(t *models.Transaction) bool {
+		return (filter.AccountID == "" || t.AccountID == filter.AccountID) &&
+			(filter.Type == "" || t.Type == filter.Type) &&
+			(filter.From.IsZero() || !t.CreatedAt.Before(filter.From)) &&
+			(filter.To.IsZero() || t.CreatedAt.Before(filter.To)) &&
+			(after == nil || newestFirst(after, t)) &&
+			(before == nil || newestFirst(t, before))
+	})
+	sortBy(transactions, newestFirst)
+	if filter.Before != nil && limit >= 0 && limit < len(transactions) {
+		return transactions[len(transactions)-limit:], nil
+	}
+	return page(transactions, limit, 0), nil
+}
+
+// atCursor is a posting at cursor's position, to compare others with.
+func atCursor(cursor *repository.TransactionCursor) *models.Transaction {
+	if cursor == nil {
+		return nil
+	}
+	return &models.Transaction{CreatedAt: cursor.CreatedAt, ID: cursor.ID}
+}
+
+func (s *Store) GetTransactionByID(id string) (*models.Transaction, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.Transaction](r, id)
+}
+
+func (s *Store) GetTransactionByIDInTx(tx repository.Tx, id string) (*models.Transaction, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byID[models.Transaction](r, id)
+}
+
+func (s *Store) GetReversedAmountsInTx(tx repository.Tx, transactionIDs []string) (map[string]models.Amount, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	amounts := make(map[string]models.Amount)
+	for _, t := range reversalsOf(r, transactionIDs) {
+		amounts[*t.ReversalOfID] += t.Amount
+	}
+	return amounts, nil
+}
+
+func (s *Store) GetReversalsOf(transactionIDs []string) ([]models.Transaction, error) {
+	r := s.read()
+	defer r.unlock()
+
+	reversals := reversalsOf(r, transactionIDs)
+	sortBy(reversals, createdFirst)
+	return reversals, nil
+}
+
+func reversalsOf(r read, transactionIDs []string) []models.Transaction {
+	return all(r, func(t *models.Transaction) bool {
+		return t.ReversalOfID != nil && slices.Contains(transactionIDs, *t.ReversalOfID)
+	})
+}
+
+func (s *Store) GetChainHeadInTx(tx repository.Tx, accountID string) (*models.Transaction, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	head := r.chainHead(accountID)
+	if head == nil || !r.visible(tableOf[models.Transaction](), head) {
+		return nil, nil
+	}
+	copied := *head
+	return &copied, nil
+}
+
+func (s *Store) GetPostingsAfterSequence(accountID string, sequence int64, limit int) ([]models.Transaction, error) {
+	r := s.read()
+	defer r.unlock()
+
+	postings := all(r, func(t *models.Transaction) bool {
+		return t.AccountID == accountID && t.Sequence > sequence
+	})
+	sortBy(postings, func(a, b *models.Transaction) bool { return a.Sequence < b.Sequence })
+	return page(postings, limit, 0), nil
+}
+
+func (s *Store) GetPostingsBetween(accountID string, from, to time.Time, limit int) ([]models.Transaction, error) {
+	r := s.read()
+	defer r.unlock()
+
+	postings := all(r, func(t *models.Transaction) bool {
+		return t.AccountID == accountID && !t.CreatedAt.Before(from) && t.CreatedAt.Before(to)
+	})
+	sortBy(postings, createdFirst)
+	return page(postings, limit, 0), nil
+}
+
+func (s *Store) SumPostings(accountID string, from, to time.Time) (models.Amount, error) {
+	r := s.read()
+	defer r.unlock()
+
+	var total models.Amount
+	for _, t := range all(r, func(t *models.Transaction) bool {
+		return t.AccountID == accountID && !t.CreatedAt.Before(from) && !t.CreatedAt.After(to)
+	}) {
+		total += t.SignedAmount()
+	}
+	return total, nil
+}
+
+func (s *Store) UpsertBalanceSnapshotInTx(tx repository.Tx, snapshot *models.BalanceSnapshot) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	schema := tableOf[models.BalanceSnapshot]()
+	key := keyOf(schema, snapshot)
+	if err := r.lock(key); err != nil {
+		return err
+	}
+
+	existing := all(r, func(b *models.BalanceSnapshot) bool {
+		return b.AccountID == snapshot.AccountID && b.Day.Equal(snapshot.Day)
+	})
+	if len(existing) == 0 {
+		return insert(r, snapshot)
+	}
+
+	merged := existing[0]
+	merged.ClosingBalance = snapshot.ClosingBalance
+	merged.TotalDebits += snapshot.TotalDebits
+	merged.TotalCredits += snapshot.TotalCredits
+	merged.UpdatedAt = snapshot.UpdatedAt
+	r.write(schema, key, &merged)
+	return nil
+}
+
+func (s *Store) GetLastBalanceSnapshotBefore(accountID string, day time.Time) (*models.BalanceSnapshot, error) {
+	r := s.read()
+	defer r.unlock()
+
+	snapshot, err := first(all(r, func(b *models.BalanceSnapshot) bool {
+		return b.AccountID == accountID && b.Day.Before(day)
+	}), func(a, b *models.BalanceSnapshot) bool { return a.Day.After(b.Day) })
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return snapshot, err
+}
+
+func (s *Store) GetBalanceSnapshots(accountID string, from, to time.Time) ([]models.BalanceSnapshot, error) {
+	r := s.read()
+	defer r.unlock()
+
+	snapshots := all(r, func(b *models.BalanceSnapshot) bool {
+		return b.AccountID == accountID && !b.Day.Before(from) && !b.Day.After(to)
+	})
+	sortBy(snapshots, func(a, b *models.BalanceSnapshot) bool { return a.Day.Before(b.Day) })
+	return snapshots, nil
+}
+
+func (s *Store) GetIdempotencyKey(key string) (*models.IdempotencyKey, error) {
+	r := s.read()
+	defer r.unlock()
+
+	records := all(r, func(k *models.IdempotencyKey) bool { return k.Key == key })
+	if len(records) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &records[0], nil
+}
+
+// SaveIdempotencyKeyInTx stores record unless a live record with the same key
+// already exists, in which case it reports false.
+func (s *Store) SaveIdempotencyKeyInTx(tx repository.Tx, record *models.IdempotencyKey) (bool, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	schema := tableOf[models.IdempotencyKey]()
+	if err := r.prepare(schema, record); err != nil {
+		return false, err
+	}
+	key := keyOf(schema, record)
+	if err := r.lock(key); err != nil {
+		return false, err
+	}
+
+	existing := all(r, func(k *models.IdempotencyKey) bool {
+		return k.TenantID == record.TenantID && k.Key == record.Key
+	})
+	if len(existing) > 0 && existing[0].ExpiresAt.After(record.CreatedAt) {
+		return false, nil
+	}
+	r.write(schema, key, record)
+	return true, nil
+}
+
+func (s *Store) UpdateIdempotencyResponseInTx(tx repository.Tx, key string, statusCode int, body []byte) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	_, err := update(r, func(k *models.IdempotencyKey) bool { return k.Key == key }, func(k *models.IdempotencyKey) {
+		k.StatusCode = statusCode
+		k.ResponseBody = body
+	})
+	return err
+}
+
+func (s *Store) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	var deleted int64
+	err := s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		var err error
+		deleted, err = remove(r, func(k *models.IdempotencyKey) bool { return !k.ExpiresAt.After(now) })
+		return err
+	})
+	return deleted, err
+}
+
+func (s *Store) CreateFXQuote(quote *models.FXQuote) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+		return insert(r, quote)
+	})
+}
+
+func (s *Store) GetFXQuoteByIDForUpdate(tx repository.Tx, id string) (*models.FXQuote, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byIDForUpdate[models.FXQuote](r, id)
+}
+
+func (s *Store) MarkFXQuoteUsedInTx(tx repository.Tx, id, journalEntryID string, usedAt time.Time) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	_, err := update(r, func(q *models.FXQuote) bool { return q.ID == id }, func(q *models.FXQuote) {
+		q.UsedAt = &usedAt
+		q.JournalEntryID = &journalEntryID
+	})
+	return err
+}
+
+func (s *Store) CreateHoldInTx(tx repository.Tx, hold *models.Hold) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, hold)
+}
+
+func (s *Store) GetHoldByID(id string) (*models.Hold, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.Hold](r, id)
+}
+
+func (s *Store) GetHoldByIDForUpdate(tx repository.Tx, id string) (*models.Hold, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byIDForUpdate[models.Hold](r, id)
+}
+
+func (s *Store) UpdateHoldInTx(tx repository.Tx, hold *models.Hold) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return save(r, hold)
+}
+
+func (s *Store) GetExpiredHolds(now time.Time, limit int) ([]models.Hold, error) {
+	r := s.read()
+	defer r.unlock()
+
+	holds := all(r, func(h *models.Hold) bool {
+		return h.Status == models.HoldStatusActive && !h.ExpiresAt.After(now)
+	})
+	sortBy(holds, func(a, b *models.Hold) bool { return a.ExpiresAt.Before(b.ExpiresAt) })
+	return page(holds, limit, 0), nil
+}
+
+func (s *Store) CreateTransferInTx(tx repository.Tx, transfer *models.Transfer) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, transfer)
+}
+
+func (s *Store) GetTransferByID(id string) (*models.Transfer, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.Transfer](r, id)
+}
+
+func (s *Store) GetTransferByIDForUpdate(tx repository.Tx, id string) (*models.Transfer, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byIDForUpdate[models.Transfer](r, id)
+}
+
+func (s *Store) UpdateTransferInTx(tx repository.Tx, transfer *models.Transfer) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return save(r, transfer)
+}
+
+func (s *Store) GetPendingTransferIDs(limit int) ([]string, error) {
+	r := s.read()
+	defer r.unlock()
+
+	transfers := all(r, func(t *models.Transfer) bool { return t.Status == models.TransferStatusPending })
+	sortBy(transfers, func(a, b *models.Transfer) bool { return a.CreatedAt.Before(b.CreatedAt) })
+
+	var ids []string
+	for _, t := range page(transfers, limit, 0) {
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
+
+// CreateTransferBatchInTx writes the batch and its transfers.
+func (s *Store) CreateTransferBatchInTx(tx repository.Tx, batch *models.TransferBatch) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	if err := insert(r, batch); err != nil {
+		return err
+	}
+	for i := range batch.Transfers {
+		transfer := &batch.Transfers[i]
+		transfer.BatchID = &batch.ID
+		if err := insert(r, transfer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetTransferBatchByID(id string) (*models.TransferBatch, error) {
+	r := s.read()
+	defer r.unlock()
+
+	batch, err := byID[models.TransferBatch](r, id)
+	if err != nil {
+		return nil, err
+	}
+	batch.Transfers = all(r, func(t *models.Transfer) bool { return t.BatchID != nil && *t.BatchID == id })
+	sortBy(batch.Transfers, func(a, b *models.Transfer) bool { return a.BatchIndex < b.BatchIndex })
+	return batch, nil
+}
+
+func (s *Store) CreateScheduledTransferInTx(tx repository.Tx, schedule *models.ScheduledTransfer) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, schedule)
+}
+
+func (s *Store) GetScheduledTransferByID(id string) (*models.ScheduledTransfer, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.ScheduledTransfer](r, id)
+}
+
+func (s *Store) GetScheduledTransferByIDForUpdate(tx repository.Tx, id string) (*models.ScheduledTransfer, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return byIDForUpdate[models.ScheduledTransfer](r, id)
+}
+
+// ClaimDueScheduledTransferInTx locks the active schedule that has been due
+// the longest, skipping schedules another transaction already holds.
+func (s *Store) ClaimDueScheduledTransferInTx(tx repository.Tx, now time.Time) (*models.ScheduledTransfer, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	due := all(r, func(st *models.ScheduledTransfer) bool {
+		return st.Status == models.ScheduleStatusActive && st.NextRunAt != nil && !st.NextRunAt.After(now)
+	})
+	sortBy(due, func(a, b *models.ScheduledTransfer) bool { return a.NextRunAt.Before(*b.NextRunAt) })
+
+	schema := tableOf[models.ScheduledTransfer]()
+	for i := range due {
+		if r.tryLock(keyOf(schema, &due[i])) {
+			return &due[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (s *Store) UpdateScheduledTransferInTx(tx repository.Tx, schedule *models.ScheduledTransfer) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return save(r, schedule)
+}
+
+func (s *Store) CreateScheduledTransferRunInTx(tx repository.Tx, run *models.ScheduledTransferRun) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	occurred := all(r, func(existing *models.ScheduledTransferRun) bool {
+		return existing.ScheduledTransferID == run.ScheduledTransferID && existing.ScheduledFor.Equal(run.ScheduledFor)
+	})
+	if len(occurred) > 0 {
+		return ErrDuplicateKey
+	}
+	return insert(r, run)
+}
+
+func (s *Store) GetScheduledTransferRuns(scheduleID string, limit, offset int) ([]models.ScheduledTransferRun, error) {
+	r := s.read()
+	defer r.unlock()
+
+	runs := all(r, func(run *models.ScheduledTransferRun) bool { return run.ScheduledTransferID == scheduleID })
+	sortBy(runs, func(a, b *models.ScheduledTransferRun) bool { return a.ScheduledFor.After(b.ScheduledFor) })
+	runs = page(runs, limit, offset)
+	for i := range runs {
+		if transfer, err := byID[models.Transfer](r, runs[i].TransferID); err == nil {
+			runs[i].Transfer = transfer
+		}
+	}
+	return runs, nil
+}
+
+func (s *Store) CreateOutboxEventInTx(tx repository.Tx, event *models.OutboxEvent) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return insert(r, event)
+}
+
+// ClaimUndispatchedEventsInTx locks up to limit events that have not been
+// fanned out to webhooks yet, skipping those another dispatcher holds.
+func (s *Store) ClaimUndispatchedEventsInTx(tx repository.Tx, limit int) ([]models.OutboxEvent, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	pending := all(r, func(e *models.OutboxEvent) bool { return e.DispatchedAt == nil })
+	sortBy(pending, func(a, b *models.OutboxEvent) bool { return a.CreatedAt.Before(b.CreatedAt) })
+
+	schema := tableOf[models.OutboxEvent]()
+	var events []models.OutboxEvent
+	for i := range pending {
+		if len(events) == limit {
+			break
+		}
+		if r.tryLock(keyOf(schema, &pending[i])) {
+			events = append(events, pending[i])
+		}
+	}
+	return events, nil
+}
+
+func (s *Store) MarkEventsDispatchedInTx(tx repository.Tx, ids []string, at time.Time) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	_, err := update(r, func(e *models.OutboxEvent) bool { return slices.Contains(ids, e.ID) }, func(e *models.OutboxEvent) {
+		e.DispatchedAt = &at
+	})
+	return err
+}
+
+func (s *Store) GetActiveWebhookEndpointsInTx(tx repository.Tx) ([]models.WebhookEndpoint, error) {
+	r := s.inTx(tx)
+	defer r.unlock()
+	return all(r, func(e *models.WebhookEndpoint) bool { return e.Active }), nil
+}
+
+// CreateWebhookDeliveriesInTx writes the deliveries, skipping those already
+// made for the same event and endpoint.
+func (s *Store) CreateWebhookDeliveriesInTx(tx repository.Tx, deliveries []models.WebhookDelivery) error {
+	r := s.inTx(tx)
+	defer r.unlock()
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		made := all(r, func(d *models.WebhookDelivery) bool {
+			return d.EventID == delivery.EventID && d.EndpointID == delivery.EndpointID
+		})
+		if len(made) > 0 {
+			continue
+		}
+		if err := insert(r, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimDueWebhookDeliveries leases up to limit due deliveries by pushing
+// their next attempt past lease, so no other dispatcher picks them up while
+// they are being sent.
+func (s *Store) ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var claimed []models.WebhookDelivery
+	err := s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		due := all(r, func(d *models.WebhookDelivery) bool {
+			return d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(now)
+		})
+		sortBy(due, func(a, b *models.WebhookDelivery) bool { return a.NextAttemptAt.Before(b.NextAttemptAt) })
+
+		schema := tableOf[models.WebhookDelivery]()
+		for i := range due {
+			if len(claimed) == limit {
+				break
+			}
+			delivery := due[i]
+			key := keyOf(schema, &delivery)
+			if !r.tryLock(key) {
+				continue
+			}
+			delivery.NextAttemptAt = now.Add(lease)
+			r.touch(schema, &delivery)
+			r.write(schema, key, &delivery)
+			claimed = append(claimed, delivery)
+		}
+		return nil
+	})
+	if err != nil || len(claimed) == 0 {
+		return nil, err
+	}
+
+	r := s.read()
+	defer r.unlock()
+	for i := range claimed {
+		claimed[i].Event, _ = byID[models.OutboxEvent](r, claimed[i].EventID)
+		claimed[i].Endpoint, _ = byID[models.WebhookEndpoint](r, claimed[i].EndpointID)
+	}
+	return claimed, nil
+}
+
+// UpdateWebhookDeliveryAttempt stores the outcome of an attempt to send
+// delivery.
+func (s *Store) UpdateWebhookDeliveryAttempt(delivery *models.WebhookDelivery) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		_, err := update(r, func(d *models.WebhookDelivery) bool { return d.ID == delivery.ID }, func(d *models.WebhookDelivery) {
+			d.Status = delivery.Status
+			d.Attempts = delivery.Attempts
+			d.NextAttemptAt = delivery.NextAttemptAt
+			d.LastStatusCode = delivery.LastStatusCode
+			d.LastError = delivery.LastError
+			d.DeliveredAt = delivery.DeliveredAt
+		})
+		return err
+	})
+}
+
+func (s *Store) GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error) {
+	r := s.read()
+	defer r.unlock()
+
+	delivery, err := byID[models.WebhookDelivery](r, id)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Event, _ = byID[models.OutboxEvent](r, delivery.EventID)
+	return delivery, nil
+}
+
+func (s *Store) GetWebhookDeliveries(endpointID string, status models.WebhookDeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error) {
+	r := s.read()
+	defer r.unlock()
+
+	deliveries := all(r, func(d *models.WebhookDelivery) bool {
+		return d.EndpointID == endpointID && (status == "" || d.Status == status)
+	})
+	sortBy(deliveries, func(a, b *models.WebhookDelivery) bool { return a.CreatedAt.After(b.CreatedAt) })
+	deliveries = page(deliveries, limit, offset)
+	for i := range deliveries {
+		deliveries[i].Event, _ = byID[models.OutboxEvent](r, deliveries[i].EventID)
+	}
+	return deliveries, nil
+}
+
+func (s *Store) CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+		return insert(r, endpoint)
+	})
+}
+
+func (s *Store) GetWebhookEndpoints() ([]models.WebhookEndpoint, error) {
+	r := s.read()
+	defer r.unlock()
+
+	endpoints := all[models.WebhookEndpoint](r, nil)
+	sortBy(endpoints, func(a, b *models.WebhookEndpoint) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return endpoints, nil
+}
+
+func (s *Store) GetWebhookEndpointByID(id string) (*models.WebhookEndpoint, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.WebhookEndpoint](r, id)
+}
+
+func (s *Store) UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+		return save(r, endpoint)
+	})
+}
+
+// DeleteWebhookEndpoint removes the endpoint together with its deliveries.
+func (s *Store) DeleteWebhookEndpoint(id string) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		if _, err := remove(r, func(d *models.WebhookDelivery) bool { return d.EndpointID == id }); err != nil {
+			return err
+		}
+		_, err := remove(r, func(e *models.WebhookEndpoint) bool { return e.ID == id })
+		return err
+	})
+}
+
+func (s *Store) CreateAPIKey(key *models.APIKey) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		if len(all(r, func(k *models.APIKey) bool { return k.Prefix == key.Prefix })) > 0 {
+			return ErrDuplicateKey
+		}
+		return insert(r, key)
+	})
+}
+
+func (s *Store) GetAPIKeys() ([]models.APIKey, error) {
+	r := s.read()
+	defer r.unlock()
+
+	keys := all[models.APIKey](r, nil)
+	sortBy(keys, func(a, b *models.APIKey) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return keys, nil
+}
+
+func (s *Store) GetAPIKeyByID(id string) (*models.APIKey, error) {
+	r := s.read()
+	defer r.unlock()
+	return byID[models.APIKey](r, id)
+}
+
+func (s *Store) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	r := s.read()
+	defer r.unlock()
+
+	keys := all(r, func(k *models.APIKey) bool { return k.Prefix == prefix })
+	if len(keys) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return &keys[0], nil
+}
+
+// RevokeAPIKey marks the key revoked, keeping the time of an earlier
+// revocation.
+func (s *Store) RevokeAPIKey(id string, at time.Time) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		_, err := update(r, func(k *models.APIKey) bool { return k.ID == id && k.RevokedAt == nil }, func(k *models.APIKey) {
+			k.RevokedAt = &at
+		})
+		return err
+	})
+}
+
+func (s *Store) TouchAPIKey(id string, at time.Time) error {
+	return s.Transaction(func(tx repository.Tx) error {
+		r := s.inTx(tx)
+		defer r.unlock()
+
+		_, err := update(r, func(k *models.APIKey) bool { return k.ID == id }, func(k *models.APIKey) {
+			k.LastUsedAt = &at
+		})
+		return err
+	})
+}
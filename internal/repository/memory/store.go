@@ -0,0 +1,573 @@
+// Package memory implements repository.LedgerStore in memory, so that the
+// ledger service can run, and be tested, without a database.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/tenant"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+var ErrDuplicateKey = errors.New("duplicate key value")
+
+// Store keeps the ledger in memory with the semantics the service relies on
+// from Postgres: a transaction sees its own writes, which become visible to
+// others only when it commits; rows it locks or writes stay locked until it
+// ends; a transaction that would wait on itself fails with
+// repository.ErrDeadlock; and the Claim methods skip rows another
+// transaction holds. Reads outside a transaction see committed data.
+type Store struct {
+	db     *database
+	tenant string
+}
+
+var _ repository.LedgerStore = (*Store)(nil)
+
+type database struct {
+	mu       sync.Mutex
+	released *sync.Cond
+	// tables holds the committed rows of each table, by primary key. Rows
+	// are never changed in place, only replaced, so they can be shared with
+	// snapshots.
+	tables map[string]map[string]any
+	locks  map[string]*txState
+	// heads indexes the committed postings by account: the one with the
+	// highest sequence, the head of the account's hash chain. Postings are
+	// only ever inserted, so it only moves forward.
+	heads map[string]*models.Transaction
+	// now stamps the rows it creates and updates, like GORM's NowFunc.
+	now func() time.Time
+}
+
+// txState is a transaction's uncommitted writes and the row locks it holds.
+// A nil row in writes is a deleted one.
+type txState struct {
+	writes     map[string]map[string]any
+	heads      map[string]*models.Transaction
+	held       []string
+	waitingFor string
+}
+
+// memTx is the repository.Tx of a Store. Scoping a transaction to a tenant
+// gives another memTx sharing the same state.
+type memTx struct {
+	state  *txState
+	tenant string
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithNowFunc sets the clock that timestamps created and updated rows, in
+// place of time.Now.
+func WithNowFunc(now func() time.Time) Option {
+	return func(s *Store) {
+		s.db.now = now
+	}
+}
+
+func NewStore(opts ...Option) *Store {
+	db := &database{
+		tables: make(map[string]map[string]any),
+		locks:  make(map[string]*txState),
+		heads:  make(map[string]*models.Transaction),
+		now:    time.Now,
+	}
+	db.released = sync.NewCond(&db.mu)
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithContext returns a store scoped to the tenant on ctx.
+func (s *Store) WithContext(ctx context.Context) repository.LedgerStore {
+	tenantID, _ := tenant.FromContext(ctx)
+	return &Store{db: s.db, tenant: tenantID}
+}
+
+// Transaction runs fn in a transaction that commits when fn returns nil.
+func (s *Store) Transaction(fn func(tx repository.Tx) error) error {
+	state := &txState{writes: make(map[string]map[string]any), heads: make(map[string]*models.Transaction)}
+	defer s.db.release(state, 0)
+
+	if err := fn(&memTx{state: state, tenant: s.tenant}); err != nil {
+		return err
+	}
+	s.db.commit(state)
+	return nil
+}
+
+// Savepoint runs fn in tx, undoing its writes and releasing the locks it
+// took when it returns an error.
+func (s *Store) Savepoint(tx repository.Tx, fn func(tx repository.Tx) error) error {
+	t := tx.(*memTx)
+
+	s.db.mu.Lock()
+	writes := make(map[string]map[string]any, len(t.state.writes))
+	for table, rows := range t.state.writes {
+		writes[table] = make(map[string]any, len(rows))
+		for key, row := range rows {
+			writes[table][key] = row
+		}
+	}
+	heads := maps.Clone(t.state.heads)
+	held := len(t.state.held)
+	s.db.mu.Unlock()
+
+	if err := fn(tx); err != nil {
+		s.db.mu.Lock()
+		t.state.writes = writes
+		t.state.heads = heads
+		s.db.mu.Unlock()
+		s.db.release(t.state, held)
+		return err
+	}
+	return nil
+}
+
+// ScopeTx returns tx scoped to tenantID.
+func (s *Store) ScopeTx(tx repository.Tx, tenantID string) (repository.Tx, error) {
+	return &memTx{state: tx.(*memTx).state, tenant: tenantID}, nil
+}
+
+// ReadSnapshot calls fn with a store over a copy of the committed data.
+func (s *Store) ReadSnapshot(fn func(store repository.LedgerStore) error) error {
+	s.db.mu.Lock()
+	snapshot := &database{
+		tables: make(map[string]map[string]any, len(s.db.tables)),
+		locks:  make(map[string]*txState),
+		heads:  maps.Clone(s.db.heads),
+		now:    s.db.now,
+	}
+	snapshot.released = sync.NewCond(&snapshot.mu)
+	for table, rows := range s.db.tables {
+		snapshot.tables[table] = make(map[string]any, len(rows))
+		for key, row := range rows {
+			snapshot.tables[table][key] = row
+		}
+	}
+	s.db.mu.Unlock()
+
+	return fn(&Store{db: snapshot, tenant: s.tenant})
+}
+
+func (db *database) commit(state *txState) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for table, rows := range state.writes {
+		committed := db.tables[table]
+		if committed == nil {
+			committed = make(map[string]any)
+			db.tables[table] = committed
+		}
+		for key, row := range rows {
+			if row == nil {
+				delete(committed, key)
+			} else {
+				committed[key] = row
+			}
+		}
+	}
+	for accountID, head := range state.heads {
+		if current := db.heads[accountID]; current == nil || head.Sequence >= current.Sequence {
+			db.heads[accountID] = head
+		}
+	}
+}
+
+// release gives up the locks state took after its first keep ones.
+func (db *database) release(state *txState, keep int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, key := range state.held[keep:] {
+		delete(db.locks, key)
+	}
+	state.held = state.held[:keep]
+	db.released.Broadcast()
+}
+
+// read is what a statement sees: the committed rows, overlaid with the
+// writes of its transaction when it runs in one, restricted to a tenant when
+// one is set. It is only used with db.mu held.
+type read struct {
+	db     *database
+	tx     *txState
+	tenant string
+}
+
+// read locks the store and returns what a statement outside a transaction
+// sees. The caller unlocks it.
+func (s *Store) read() read {
+	s.db.mu.Lock()
+	return read{db: s.db, tenant: s.tenant}
+}
+
+// inTx locks the store and returns what a statement in tx sees. The caller
+// unlocks it.
+func (s *Store) inTx(tx repository.Tx) read {
+	t := tx.(*memTx)
+	s.db.mu.Lock()
+	return read{db: s.db, tx: t.state, tenant: t.tenant}
+}
+
+func (r read) unlock() {
+	r.db.mu.Unlock()
+}
+
+// lock takes the row lock on key for r's transaction, waiting for the
+// transaction that holds it to end.
+func (r read) lock(key string) error {
+	for {
+		holder := r.db.locks[key]
+		if holder == nil {
+			r.db.locks[key] = r.tx
+			r.tx.held = append(r.tx.held, key)
+			return nil
+		}
+		if holder == r.tx {
+			return nil
+		}
+		if r.waitsOn(holder) {
+			return repository.ErrDeadlock
+		}
+		r.tx.waitingFor = key
+		r.db.released.Wait()
+		r.tx.waitingFor = ""
+	}
+}
+
+// tryLock takes the row lock on key unless another transaction holds it.
+func (r read) tryLock(key string) bool {
+	holder := r.db.locks[key]
+	if holder != nil && holder != r.tx {
+		return false
+	}
+	if holder == nil {
+		r.db.locks[key] = r.tx
+		r.tx.held = append(r.tx.held, key)
+	}
+	return true
+}
+
+// waitsOn reports whether holder is, through the locks it waits for,
+// waiting on r's transaction.
+func (r read) waitsOn(holder *txState) bool {
+	for range len(r.db.locks) + 1 {
+		if holder == r.tx {
+			return true
+		}
+		if holder == nil || holder.waitingFor == "" {
+			return false
+		}
+		holder = r.db.locks[holder.waitingFor]
+	}
+	return false
+}
+
+var schemas sync.Map
+
+func schemaOf(row any) *schema.Schema {
+	s, err := schema.Parse(row, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("memory: %v", err))
+	}
+	return s
+}
+
+func tableOf[T any]() *schema.Schema {
+	return schemaOf(new(T))
+}
+
+// keyOf returns the primary key of row, which also names its row lock.
+func keyOf(s *schema.Schema, row any) string {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	parts := make([]string, 0, len(s.PrimaryFields)+1)
+	parts = append(parts, s.Table)
+	for _, field := range s.PrimaryFields {
+		value, _ := field.ValueOf(context.Background(), rv)
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, "/")
+}
+
+// visible reports whether row belongs to r's tenant, if it has one.
+func (r read) visible(s *schema.Schema, row any) bool {
+	field := s.LookUpField("TenantID")
+	if r.tenant == "" || field == nil {
+		return true
+	}
+	value, _ := field.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(row)))
+	return value == r.tenant
+}
+
+// all returns copies of the rows of T that r sees and match accepts, in no
+// particular order.
+func all[T any](r read, match func(*T) bool) []T {
+	s := tableOf[T]()
+	var rows []T
+	add := func(row any) {
+		if row == nil || !r.visible(s, row) {
+			return
+		}
+		if match == nil || match(row.(*T)) {
+			rows = append(rows, *row.(*T))
+		}
+	}
+
+	var written map[string]any
+	if r.tx != nil {
+		written = r.tx.writes[s.Table]
+	}
+	for key, row := range r.db.tables[s.Table] {
+		if _, ok := written[key]; !ok {
+			add(row)
+		}
+	}
+	for _, row := range written {
+		add(row)
+	}
+	return rows
+}
+
+// byID returns a copy of the row of T with the given ID, or
+// repository.ErrNotFound.
+func byID[T any](r read, id string) (*T, error) {
+	s := tableOf[T]()
+	key := s.Table + "/" + id
+
+	row, ok := any(nil), false
+	if r.tx != nil {
+		row, ok = r.tx.writes[s.Table][key]
+	}
+	if !ok {
+		row = r.db.tables[s.Table][key]
+	}
+	if row == nil || !r.visible(s, row) {
+		return nil, repository.ErrNotFound
+	}
+	copied := *row.(*T)
+	return &copied, nil
+}
+
+// byIDForUpdate locks the row of T with the given ID and returns it.
+func byIDForUpdate[T any](r read, id string) (*T, error) {
+	if err := r.lock(tableOf[T]().Table + "/" + id); err != nil {
+		return nil, err
+	}
+	return byID[T](r, id)
+}
+
+// first returns the first of rows once sorted by less, or
+// repository.ErrNotFound when there are none.
+func first[T any](rows []T, less func(a, b *T) bool) (*T, error) {
+	if len(rows) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	sortBy(rows, less)
+	return &rows[0], nil
+}
+
+func sortBy[T any](rows []T, less func(a, b *T) bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(&rows[i], &rows[j])
+	})
+}
+
+// page applies a limit and offset as SQL does; a negative limit is none.
+func page[T any](rows []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return nil
+		}
+		rows = rows[offset:]
+	}
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// insert stamps row with r's tenant, its column defaults and its creation
+// time, as the database would, and writes a copy of it.
+func insert[T any](r read, row *T) error {
+	s := tableOf[T]()
+	if err := r.prepare(s, row); err != nil {
+		return err
+	}
+
+	key := keyOf(s, row)
+	if err := r.lock(key); err != nil {
+		return err
+	}
+	if exists(r, s, key) {
+		return fmt.Errorf("%w: %s", ErrDuplicateKey, key)
+	}
+	r.write(s, key, row)
+	return nil
+}
+
+// save writes a copy of row over the stored one, updating its UpdatedAt.
+func save[T any](r read, row *T) error {
+	s := tableOf[T]()
+	key := keyOf(s, row)
+	if err := r.lock(key); err != nil {
+		return err
+	}
+	if !exists(r, s, key) {
+		return insert(r, row)
+	}
+	r.touch(s, row)
+	r.write(s, key, row)
+	return nil
+}
+
+// update applies change to a copy of each row of T that r sees and match
+// accepts, locking it first, and reports how many rows it changed.
+func update[T any](r read, match func(*T) bool, change func(*T)) (int64, error) {
+	s := tableOf[T]()
+	var n int64
+	for _, row := range all(r, match) {
+		key := keyOf(s, &row)
+		if err := r.lock(key); err != nil {
+			return n, err
+		}
+		change(&row)
+		r.touch(s, &row)
+		r.write(s, key, &row)
+		n++
+	}
+	return n, nil
+}
+
+// remove deletes the rows of T that r sees and match accepts.
+func remove[T any](r read, match func(*T) bool) (int64, error) {
+	s := tableOf[T]()
+	var n int64
+	for _, row := range all(r, match) {
+		key := keyOf(s, &row)
+		if err := r.lock(key); err != nil {
+			return n, err
+		}
+		r.write(s, key, nil)
+		n++
+	}
+	return n, nil
+}
+
+func exists(r read, s *schema.Schema, key string) bool {
+	if r.tx != nil {
+		if row, ok := r.tx.writes[s.Table][key]; ok {
+			return row != nil
+		}
+	}
+	_, ok := r.db.tables[s.Table][key]
+	return ok
+}
+
+// write stores a copy of row, without its associations, in r's
+// transaction; a nil row deletes it.
+func (r read) write(s *schema.Schema, key string, row any) {
+	if row != nil {
+		copied := reflect.New(reflect.TypeOf(row).Elem()).Elem()
+		copied.Set(reflect.ValueOf(row).Elem())
+		for _, rel := range s.Relationships.Relations {
+			if rel.Field.Schema != s {
+				continue
+			}
+			field := copied.FieldByIndex(rel.Field.StructField.Index)
+			field.Set(reflect.Zero(field.Type()))
+		}
+		row = copied.Addr().Interface()
+	}
+	if r.tx.writes[s.Table] == nil {
+		r.tx.writes[s.Table] = make(map[string]any)
+	}
+	r.tx.writes[s.Table][key] = row
+
+	if posting, ok := row.(*models.Transaction); ok {
+		if head := r.chainHead(posting.AccountID); head == nil || posting.Sequence >= head.Sequence {
+			r.tx.heads[posting.AccountID] = posting
+		}
+	}
+}
+
+// chainHead returns the posting of the account with the highest sequence
+// that r sees, without checking its tenant.
+func (r read) chainHead(accountID string) *models.Transaction {
+	if r.tx != nil {
+		if head := r.tx.heads[accountID]; head != nil {
+			return head
+		}
+	}
+	return r.db.heads[accountID]
+}
+
+// prepare fills in what the database and the GORM callbacks would on
+// insert: the tenant of r, an ID, column defaults and timestamps. A row for another tenant is refused, like
+// the tenant callbacks of the GORM repository refuse it.
+func (r read) prepare(s *schema.Schema, row any) error {
+	ctx := context.Background()
+	rv := reflect.Indirect(reflect.ValueOf(row))
+
+	if field := s.LookUpField("TenantID"); field != nil && r.tenant != "" {
+		value, zero := field.ValueOf(ctx, rv)
+		if zero {
+			if err := field.Set(ctx, rv, r.tenant); err != nil {
+				return err
+			}
+		} else if value != r.tenant {
+			return repository.ErrTenantMismatch
+		}
+	}
+
+	now := r.db.now().Truncate(time.Microsecond)
+	for _, field := range s.Fields {
+		if _, zero := field.ValueOf(ctx, rv); !zero {
+			continue
+		}
+		var value any
+		switch {
+		case field.AutoCreateTime > 0 || field.AutoUpdateTime > 0:
+			value = now
+		case field.DefaultValueInterface != nil:
+			value = field.DefaultValueInterface
+		case field == s.PrioritizedPrimaryField && field.Name == "ID" && field.FieldType.Kind() == reflect.String:
+			value = models.NewID()
+		default:
+			continue
+		}
+		if err := field.Set(ctx, rv, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touch sets the UpdatedAt of row, as GORM does on save and update.
+func (r read) touch(s *schema.Schema, row any) {
+	ctx := context.Background()
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	for _, field := range s.Fields {
+		if field.AutoUpdateTime > 0 {
+			field.Set(ctx, rv, r.db.now().Truncate(time.Microsecond))
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"ledger/internal/repository"
+	"ledger/internal/repository/memory"
+	"ledger/internal/repository/storetest"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.LedgerStore {
+		return memory.NewStore()
+	})
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"time"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned by lookups of a single record that does not exist
+// or belongs to another tenant.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// ErrDeadlock is returned by stores that detect a deadlock themselves
+// instead of leaving it to the database.
+var ErrDeadlock = errors.New("deadlock detected")
+
+// Postgres error codes for transactions that lost a race and can simply be
+// run again.
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// SQLite primary result codes for a database another connection holds
+// locked past the busy timeout.
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// Tx is a unit of work opened by LedgerStore.Transaction. Only the store
+// that opened it knows what it holds; it is passed back to that store's
+// InTx and ForUpdate methods, and is done once the Transaction callback
+// returns.
+type Tx interface{}
+
+// TransactionFilter narrows a listing of postings to an account, a posting
+// type and a creation time range, From inclusive and To exclusive. Zero
+// fields do not narrow it.
+//
+// After and Before page through the listing: After keeps the postings older
+// than a cursor, Before those newer than it.
+type TransactionFilter struct {
+	AccountID string
+	Type      models.TransactionType
+	From      time.Time
+	To        time.Time
+	After     *TransactionCursor
+	Before    *TransactionCursor
+}
+
+// TransactionCursor is a position in the newest-first order of postings,
+// which orders postings created at the same time by descending ID.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// LedgerStore is the storage the ledger service runs on. Reads outside a Tx
+// see committed data; the ForUpdate methods lock the rows they return until
+// their Tx ends, and the Claim methods skip rows another Tx has locked.
+// A store with a tenant on its context only sees and writes that tenant's
+// records.
+type LedgerStore interface {
+	WithContext(ctx context.Context) LedgerStore
+	Transaction(fn func(tx Tx) error) error
+	Savepoint(tx Tx, fn func(tx Tx) error) error
+	ScopeTx(tx Tx, tenantID string) (Tx, error)
+	ReadSnapshot(fn func(store LedgerStore) error) error
+
+	CreateAccountInTx(tx Tx, account *models.Account) error
+	GetAccountByID(id string) (*models.Account, error)
+	GetAccountByIDForUpdate(tx Tx, id string) (*models.Account, error)
+	GetSystemAccountForUpdate(tx Tx, kind, currency string) (*models.Account, error)
+	GetSystemAccount(tx Tx, kind, currency string) (*models.Account, error)
+	GetAccountsForVerification() ([]models.Account, error)
+	GetAccounts(limit, offset int) ([]models.Account, error)
+	UpdateAccountBalanceInTx(tx Tx, id string, newBalance models.Amount) error
+	UpdateAccountHeldBalanceInTx(tx Tx, id string, heldBalance models.Amount) error
+	UpdateAccountPolicyInTx(tx Tx, id string, overdraftLimit models.Amount, neverNegative bool) error
+	UpdateAccountStatusInTx(tx Tx, id string, status models.AccountStatus) error
+	CreateAccountStatusChangeInTx(tx Tx, change *models.AccountStatusChange) error
+	GetAccountStatusChanges(accountID string) ([]models.AccountStatusChange, error)
+	CreateAuditLogInTx(tx Tx, entry *models.AuditLog) error
+	GetAuditLogs(entityType, entityID string) ([]models.AuditLog, error)
+
+	CreateJournalEntryInTx(tx Tx, entry *models.JournalEntry) error
+	GetJournalEntryByID(id string) (*models.JournalEntry, error)
+	GetJournalEntryByIDInTx(tx Tx, id string) (*models.JournalEntry, error)
+	GetTransactions(filter TransactionFilter, limit int) ([]models.Transaction, error)
+	GetTransactionByID(id string) (*models.Transaction, error)
+	GetTransactionByIDInTx(tx Tx, id string) (*models.Transaction, error)
+	GetReversedAmountsInTx(tx Tx, transactionIDs []string) (map[string]models.Amount, error)
+	GetReversalsOf(transactionIDs []string) ([]models.Transaction, error)
+	GetChainHeadInTx(tx Tx, accountID string) (*models.Transaction, error)
+	GetPostingsAfterSequence(accountID string, sequence int64, limit int) ([]models.Transaction, error)
+	GetPostingsBetween(accountID string, from, to time.Time, limit int) ([]models.Transaction, error)
+	SumPostings(accountID string, from, to time.Time) (models.Amount, error)
+
+	UpsertBalanceSnapshotInTx(tx Tx, snapshot *models.BalanceSnapshot) error
+	GetLastBalanceSnapshotBefore(accountID string, day time.Time) (*models.BalanceSnapshot, error)
+	GetBalanceSnapshots(accountID string, from, to time.Time) ([]models.BalanceSnapshot, error)
+
+	GetIdempotencyKey(key string) (*models.IdempotencyKey, error)
+	SaveIdempotencyKeyInTx(tx Tx, record *models.IdempotencyKey) (bool, error)
+	UpdateIdempotencyResponseInTx(tx Tx, key string, statusCode int, body []byte) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
+
+	CreateFXQuote(quote *models.FXQuote) error
+	GetFXQuoteByIDForUpdate(tx Tx, id string) (*models.FXQuote, error)
+	MarkFXQuoteUsedInTx(tx Tx, id, journalEntryID string, usedAt time.Time) error
+
+	CreateHoldInTx(tx Tx, hold *models.Hold) error
+	GetHoldByID(id string) (*models.Hold, error)
+	GetHoldByIDForUpdate(tx Tx, id string) (*models.Hold, error)
+	UpdateHoldInTx(tx Tx, hold *models.Hold) error
+	GetExpiredHolds(now time.Time, limit int) ([]models.Hold, error)
+
+	CreateTransferInTx(tx Tx, transfer *models.Transfer) error
+	GetTransferByID(id string) (*models.Transfer, error)
+	GetTransferByIDForUpdate(tx Tx, id string) (*models.Transfer, error)
+	UpdateTransferInTx(tx Tx, transfer *models.Transfer) error
+	GetPendingTransferIDs(limit int) ([]string, error)
+	CreateTransferBatchInTx(tx Tx, batch *models.TransferBatch) error
+	GetTransferBatchByID(id string) (*models.TransferBatch, error)
+
+	CreateScheduledTransferInTx(tx Tx, schedule *models.ScheduledTransfer) error
+	GetScheduledTransferByID(id string) (*models.ScheduledTransfer, error)
+	GetScheduledTransferByIDForUpdate(tx Tx, id string) (*models.ScheduledTransfer, error)
+	ClaimDueScheduledTransferInTx(tx Tx, now time.Time) (*models.ScheduledTransfer, error)
+	UpdateScheduledTransferInTx(tx Tx, schedule *models.ScheduledTransfer) error
+	CreateScheduledTransferRunInTx(tx Tx, run *models.ScheduledTransferRun) error
+	GetScheduledTransferRuns(scheduleID string, limit, offset int) ([]models.ScheduledTransferRun, error)
+
+	CreateOutboxEventInTx(tx Tx, event *models.OutboxEvent) error
+	ClaimUndispatchedEventsInTx(tx Tx, limit int) ([]models.OutboxEvent, error)
+	MarkEventsDispatchedInTx(tx Tx, ids []string, at time.Time) error
+	GetActiveWebhookEndpointsInTx(tx Tx) ([]models.WebhookEndpoint, error)
+	CreateWebhookDeliveriesInTx(tx Tx, deliveries []models.WebhookDelivery) error
+	ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	UpdateWebhookDeliveryAttempt(delivery *models.WebhookDelivery) error
+	GetWebhookDeliveryByID(id string) (*models.WebhookDelivery, error)
+	GetWebhookDeliveries(endpointID string, status models.WebhookDeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error)
+	CreateWebhookEndpoint(endpoint *models.WebhookEndpoint) error
+	GetWebhookEndpoints() ([]models.WebhookEndpoint, error)
+	GetWebhookEndpointByID(id string) (*models.WebhookEndpoint, error)
+	UpdateWebhookEndpoint(endpoint *models.WebhookEndpoint) error
+	DeleteWebhookEndpoint(id string) error
+
+	CreateAPIKey(key *models.APIKey) error
+	GetAPIKeys() ([]models.APIKey, error)
+	GetAPIKeyByID(id string) (*models.APIKey, error)
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, error)
+	RevokeAPIKey(id string, at time.Time) error
+	TouchAPIKey(id string, at time.Time) error
+}
+
+var _ LedgerStore = (*LedgerRepository)(nil)
+
+// IsRetryable reports whether err aborted a transaction that lost a race
+// and can simply be run again: a serialization failure, a deadlock or, on
+// SQLite, a database that stayed locked.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrDeadlock) {
+		return true
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
+
+// Savepoint runs fn in a savepoint of tx, rolling back only what fn did when
+// it returns an error.
+func (r *LedgerRepository) Savepoint(tx Tx, fn func(tx Tx) error) error {
+	return gormTx(tx).Transaction(func(sp *gorm.DB) error {
+		return fn(sp)
+	})
+}
+
+// gormTx returns the GORM transaction behind a Tx opened by a
+// LedgerRepository.
+func gormTx(tx Tx) *gorm.DB {
+	return tx.(*gorm.DB)
+}
@@ -0,0 +1,83 @@
+package storetest
+
+import (
+	"ledger/internal/config"
+	"ledger/internal/migrations"
+	"ledger/internal/repository"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// PostgresDSNEnv names the variable holding the DSN of a Postgres database
+// to run the tests against. They are skipped when it is not set. The
+// database is migrated and shared between tests, which keep apart by
+// working in tenants of their own.
+const PostgresDSNEnv = "LEDGER_TEST_POSTGRES_DSN"
+
+// OpenSQLite returns a migrated SQLite database in a temporary directory,
+// closed when the test ends.
+func OpenSQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := config.ConnectDatabase(&config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		Path:   filepath.Join(t.TempDir(), "ledger.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrated(t, db)
+}
+
+// OpenPostgres returns the migrated Postgres database named by
+// PostgresDSNEnv, or skips the test.
+func OpenPostgres(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrated(t, db)
+}
+
+// NewSQLiteStore returns a repository over a database from OpenSQLite.
+func NewSQLiteStore(t *testing.T) repository.LedgerStore {
+	return repository.NewLedgerRepository(OpenSQLite(t))
+}
+
+// NewPostgresStore returns a repository over the database from
+// OpenPostgres.
+func NewPostgresStore(t *testing.T) repository.LedgerStore {
+	return repository.NewLedgerRepository(OpenPostgres(t))
+}
+
+func migrated(t testing.TB, db *gorm.DB) *gorm.DB {
+	t.Helper()
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
@@ -0,0 +1,405 @@
+// Package storetest checks that a repository.LedgerStore behaves the way the
+// ledger service relies on, so that every store passes the same tests, and
+// opens the database-backed stores for tests.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/tenant"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run runs the conformance tests against the stores open returns. Each test
+// works in a tenant of its own, so that stores over a shared database do not
+// see each other's rows.
+func Run(t *testing.T, open func(t *testing.T) repository.LedgerStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store repository.LedgerStore)
+	}{
+		{"Accounts", testAccounts},
+		{"TransactionCommitsOrRollsBack", testTransaction},
+		{"SavepointRollsBackItsWritesOnly", testSavepoint},
+		{"SystemAccountCreatedOnce", testSystemAccount},
+		{"JournalEntries", testJournalEntries},
+		{"UnbalancedJournalEntryRefused", testUnbalancedJournalEntry},
+		{"LockedUpdatesSerialize", testLockedUpdates},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"TimesInAnyZone", testTimesInAnyZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, InTenant(open(t), models.NewID()))
+		})
+	}
+}
+
+// InTenant returns store scoped to tenantID.
+func InTenant(store repository.LedgerStore, tenantID string) repository.LedgerStore {
+	return store.WithContext(tenant.WithID(context.Background(), tenantID))
+}
+
+// CreateAccount creates a customer account with balance, straight in the
+// store.
+func CreateAccount(t testing.TB, store repository.LedgerStore, balance models.Amount) *models.Account {
+	t.Helper()
+	account := &models.Account{OwnerName: "test", Currency: "USD", Balance: balance}
+	err := store.Transaction(func(tx repository.Tx) error {
+		return store.CreateAccountInTx(tx, account)
+	})
+	if err != nil {
+		t.Fatalf("CreateAccountInTx: %v", err)
+	}
+	return account
+}
+
+// Transfer writes a journal entry moving amount from one account to
+// another, without touching their balances.
+func Transfer(t testing.TB, store repository.LedgerStore, from, to string, amount models.Amount, at time.Time) *models.JournalEntry {
+	t.Helper()
+	entry := &models.JournalEntry{
+		ID:        models.NewID(),
+		Type:      models.JournalEntryTypeTransfer,
+		CreatedAt: at,
+		Postings: []models.Transaction{
+			{ID: models.NewID(), AccountID: from, Type: models.TransactionTypeDebit, Amount: amount, Currency: "USD", CreatedAt: at},
+			{ID: models.NewID(), AccountID: to, Type: models.TransactionTypeCredit, Amount: amount, Currency: "USD", CreatedAt: at},
+		},
+	}
+	err := store.Transaction(func(tx repository.Tx) error {
+		return store.CreateJournalEntryInTx(tx, entry)
+	})
+	if err != nil {
+		t.Fatalf("CreateJournalEntryInTx: %v", err)
+	}
+	return entry
+}
+
+func testAccounts(t *testing.T, store repository.LedgerStore) {
+	first := CreateAccount(t, store, 100)
+	second := CreateAccount(t, store, 0)
+	err := store.Transaction(func(tx repository.Tx) error {
+		_, err := store.GetSystemAccountForUpdate(tx, models.SystemAccountFunding, "USD")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("GetSystemAccountForUpdate: %v", err)
+	}
+
+	account, err := store.GetAccountByID(first.ID)
+	if err != nil {
+		t.Fatalf("GetAccountByID: %v", err)
+	}
+	if account.Balance != 100 || account.Status != models.AccountStatusActive || account.CreatedAt.IsZero() {
+		t.Errorf("account = %+v, want an active account with 1.00 and a creation time", account)
+	}
+
+	if _, err := store.GetAccountByID(models.NewID()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetAccountByID of a missing account = %v, want %v", err, repository.ErrNotFound)
+	}
+
+	accounts, err := store.GetAccounts(10, 0)
+	if err != nil {
+		t.Fatalf("GetAccounts: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].ID != first.ID || accounts[1].ID != second.ID {
+		t.Errorf("GetAccounts = %v, want the two customer accounts oldest first", accounts)
+	}
+	if page, _ := store.GetAccounts(1, 1); len(page) != 1 || page[0].ID != second.ID {
+		t.Errorf("GetAccounts(1, 1) = %v, want the second account", page)
+	}
+}
+
+func testTransaction(t *testing.T, store repository.LedgerStore) {
+	account := CreateAccount(t, store, 0)
+
+	failure := errors.New("failure")
+	err := store.Transaction(func(tx repository.Tx) error {
+		if err := store.UpdateAccountBalanceInTx(tx, account.ID, 500); err != nil {
+			return err
+		}
+		seen, err := store.GetAccountByIDForUpdate(tx, account.ID)
+		if err != nil {
+			return err
+		}
+		if seen.Balance != 500 {
+			t.Errorf("transaction sees a balance of %s, want its own write", seen.Balance)
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transaction = %v, want %v", err, failure)
+	}
+	if got, _ := store.GetAccountByID(account.ID); got.Balance != 0 {
+		t.Fatalf("rolled back write is visible: balance %s", got.Balance)
+	}
+
+	err = store.Transaction(func(tx repository.Tx) error {
+		return store.UpdateAccountBalanceInTx(tx, account.ID, 700)
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if got, _ := store.GetAccountByID(account.ID); got.Balance != 700 {
+		t.Fatalf("committed balance = %s, want 7.00", got.Balance)
+	}
+}
+
+func testSavepoint(t *testing.T, store repository.LedgerStore) {
+	account := CreateAccount(t, store, 0)
+
+	failure := errors.New("failure")
+	err := store.Transaction(func(tx repository.Tx) error {
+		if err := store.UpdateAccountBalanceInTx(tx, account.ID, 100); err != nil {
+			return err
+		}
+		err := store.Savepoint(tx, func(tx repository.Tx) error {
+			if err := store.UpdateAccountBalanceInTx(tx, account.ID, 200); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("Savepoint = %v, want %v", err, failure)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if got, _ := store.GetAccountByID(account.ID); got.Balance != 100 {
+		t.Fatalf("balance = %s, want the 1.00 written outside the savepoint", got.Balance)
+	}
+}
+
+func testSystemAccount(t *testing.T, store repository.LedgerStore) {
+	var ids []string
+	for i := 0; i < 2; i++ {
+		err := store.Transaction(func(tx repository.Tx) error {
+			account, err := store.GetSystemAccountForUpdate(tx, models.SystemAccountFXPosition, "EUR")
+			if err != nil {
+				return err
+			}
+			if !account.System || account.Currency != "EUR" {
+				t.Errorf("system account = %+v, want a EUR system account", account)
+			}
+			ids = append(ids, account.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("GetSystemAccountForUpdate: %v", err)
+		}
+	}
+	if ids[0] != ids[1] {
+		t.Fatalf("system account created twice: %v", ids)
+	}
+	if accounts, _ := store.GetAccounts(10, 0); len(accounts) != 0 {
+		t.Fatalf("GetAccounts lists system accounts: %v", accounts)
+	}
+}
+
+func testJournalEntries(t *testing.T, store repository.LedgerStore) {
+	from := CreateAccount(t, store, 0)
+	to := CreateAccount(t, store, 0)
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	entry := Transfer(t, store, from.ID, to.ID, 250, at)
+
+	stored, err := store.GetJournalEntryByID(entry.ID)
+	if err != nil {
+		t.Fatalf("GetJournalEntryByID: %v", err)
+	}
+	if len(stored.Postings) != 2 {
+		t.Fatalf("entry has %d postings, want 2", len(stored.Postings))
+	}
+	for _, posting := range stored.Postings {
+		if posting.JournalEntryID != entry.ID || posting.Amount != 250 || !posting.CreatedAt.Equal(at) {
+			t.Errorf("posting = %+v, want 2.50 of entry %s at %s", posting, entry.ID, at)
+		}
+	}
+
+	posting, err := store.GetTransactionByID(entry.Postings[1].ID)
+	if err != nil || posting.AccountID != to.ID || posting.Type != models.TransactionTypeCredit {
+		t.Fatalf("GetTransactionByID = %+v, %v, want the credit to %s", posting, err, to.ID)
+	}
+
+	total, err := store.SumPostings(to.ID, at.Add(-time.Second), at.Add(time.Second))
+	if err != nil || total != 250 {
+		t.Fatalf("SumPostings = %s, %v, want 2.50", total, err)
+	}
+}
+
+// An entry whose postings do not balance per currency is refused by the
+// store itself, whatever the service checked, and leaves nothing behind.
+func testUnbalancedJournalEntry(t *testing.T, store repository.LedgerStore) {
+	from := CreateAccount(t, store, 0)
+	to := CreateAccount(t, store, 0)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	tests := []struct {
+		name     string
+		postings []models.Transaction
+	}{
+		{"amounts differ", []models.Transaction{
+			{AccountID: from.ID, Type: models.TransactionTypeDebit, Amount: 100, Currency: "USD"},
+			{AccountID: to.ID, Type: models.TransactionTypeCredit, Amount: 101, Currency: "USD"},
+		}},
+		{"credit only", []models.Transaction{
+			{AccountID: to.ID, Type: models.TransactionTypeCredit, Amount: 100, Currency: "USD"},
+		}},
+		{"balanced across currencies only", []models.Transaction{
+			{AccountID: from.ID, Type: models.TransactionTypeDebit, Amount: 100, Currency: "USD"},
+			{AccountID: to.ID, Type: models.TransactionTypeCredit, Amount: 100, Currency: "EUR"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &models.JournalEntry{ID: models.NewID(), Type: models.JournalEntryTypeTransfer, CreatedAt: now, Postings: tt.postings}
+			for i := range entry.Postings {
+				entry.Postings[i].ID = models.NewID()
+				entry.Postings[i].CreatedAt = now
+			}
+			err := store.Transaction(func(tx repository.Tx) error {
+				return store.CreateJournalEntryInTx(tx, entry)
+			})
+			if err == nil {
+				t.Fatal("CreateJournalEntryInTx accepted an unbalanced entry")
+			}
+			if _, err := store.GetJournalEntryByID(entry.ID); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("refused entry was stored: %v", err)
+			}
+		})
+	}
+
+	// A balanced entry in the same store still goes through.
+	Transfer(t, store, from.ID, to.ID, 100, now)
+}
+
+// Transactions that read a row for update and write it back do not lose
+// each other's writes. Stores may abort such transactions with a retryable
+// error, which the service runs again.
+func testLockedUpdates(t *testing.T, store repository.LedgerStore) {
+	account := CreateAccount(t, store, 0)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := store.Transaction(func(tx repository.Tx) error {
+					locked, err := store.GetAccountByIDForUpdate(tx, account.ID)
+					if err != nil {
+						return err
+					}
+					return store.UpdateAccountBalanceInTx(tx, account.ID, locked.Balance+1)
+				})
+				if !repository.IsRetryable(err) {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Transaction: %v", err)
+		}
+	}
+	if got, _ := store.GetAccountByID(account.ID); got.Balance != writers {
+		t.Fatalf("balance = %d after %d increments", got.Balance, writers)
+	}
+}
+
+func testIdempotencyKeys(t *testing.T, store repository.LedgerStore) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	save := func(fingerprint string, createdAt, expiresAt time.Time) bool {
+		t.Helper()
+		var saved bool
+		err := store.Transaction(func(tx repository.Tx) error {
+			var err error
+			saved, err = store.SaveIdempotencyKeyInTx(tx, &models.IdempotencyKey{
+				Key:          "key",
+				Fingerprint:  fingerprint,
+				StatusCode:   201,
+				ResponseBody: []byte(`{}`),
+				CreatedAt:    createdAt,
+				ExpiresAt:    expiresAt,
+			})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("SaveIdempotencyKeyInTx: %v", err)
+		}
+		return saved
+	}
+
+	if !save("first", now, now.Add(time.Hour)) {
+		t.Fatal("first use of a key was not saved")
+	}
+	if save("second", now, now.Add(time.Hour)) {
+		t.Fatal("a live key was saved over")
+	}
+	if record, err := store.GetIdempotencyKey("key"); err != nil || record.Fingerprint != "first" {
+		t.Fatalf("GetIdempotencyKey = %+v, %v, want the first request", record, err)
+	}
+
+	if !save("third", now.Add(2*time.Hour), now.Add(3*time.Hour)) {
+		t.Fatal("an expired key was not reused")
+	}
+	if record, err := store.GetIdempotencyKey("key"); err != nil || record.Fingerprint != "third" {
+		t.Fatalf("GetIdempotencyKey = %+v, %v, want the third request", record, err)
+	}
+
+	if _, err := store.GetIdempotencyKey("other"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetIdempotencyKey of an unused key = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+// Times are instants: rows written and queries made in different zones
+// compare by the instant, not by how it is written.
+func testTimesInAnyZone(t *testing.T, store repository.LedgerStore) {
+	account := CreateAccount(t, store, 0)
+	east := time.FixedZone("UTC+5", 5*60*60)
+	west := time.FixedZone("UTC-3", -3*60*60)
+	now := time.Now().Truncate(time.Microsecond)
+
+	// Written in UTC+5, the hold expiring first reads as the later time.
+	for _, expiresAt := range []time.Time{now.Add(-time.Hour).In(east), now.Add(time.Hour).In(west)} {
+		err := store.Transaction(func(tx repository.Tx) error {
+			return store.CreateHoldInTx(tx, &models.Hold{
+				AccountID: account.ID,
+				Amount:    1,
+				Currency:  "USD",
+				Status:    models.HoldStatusActive,
+				ExpiresAt: expiresAt,
+			})
+		})
+		if err != nil {
+			t.Fatalf("CreateHoldInTx: %v", err)
+		}
+	}
+
+	expired, err := store.GetExpiredHolds(now.In(west), 10)
+	if err != nil {
+		t.Fatalf("GetExpiredHolds: %v", err)
+	}
+	if len(expired) != 1 || !expired[0].ExpiresAt.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("GetExpiredHolds = %v, want the hold that expired an hour ago", expired)
+	}
+
+	from := CreateAccount(t, store, 0)
+	Transfer(t, store, from.ID, account.ID, 100, now.In(east))
+	total, err := store.SumPostings(account.ID, now.Add(-time.Minute).In(west), now.Add(time.Minute).In(west))
+	if err != nil || total != 100 {
+		t.Fatalf("SumPostings = %s, %v, want the 1.00 posted in another zone", total, err)
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"ledger/internal/tenant"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// tenantSetting is the Postgres setting the row-level security policies
+// compare tenant_id against. Rows of other tenants are hidden unless
+// bypassSetting is on, as it is for work marked with tenant.WithBypass.
+const (
+	tenantSetting = "app.tenant_id"
+	bypassSetting = "app.bypass_rls"
+)
+
+const setSettingsSQL = "SELECT set_config($1, $2, true), set_config($3, $4, true)"
+
+var ErrTenantMismatch = errors.New("record belongs to another tenant")
+
+// registerTenantCallbacks scopes every query, update and delete on a model
+// with a TenantID to the tenant on the statement's context, and stamps that
+// tenant on the records it creates.
+func registerTenantCallbacks(db *gorm.DB) {
+	scope := func(tx *gorm.DB) {
+		tenantID, ok := tenant.FromContext(tx.Statement.Context)
+		if !ok || tenantField(tx) == nil {
+			return
+		}
+		tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, Value: tenantID},
+		}})
+	}
+	db.Callback().Query().Before("gorm:query").Register("ledger:tenant_query", scope)
+	db.Callback().Row().Before("gorm:row").Register("ledger:tenant_row", scope)
+	db.Callback().Update().Before("gorm:update").Register("ledger:tenant_update", scope)
+	db.Callback().Delete().Before("gorm:delete").Register("ledger:tenant_delete", scope)
+	db.Callback().Create().Before("gorm:create").Register("ledger:tenant_create", stampTenant)
+}
+
+// registerSettingsCallbacks sets the row-level security settings for every
+// statement on Postgres, so that the policies see the tenant of the
+// statement's context, or the bypass, and otherwise no rows at all. The
+// settings are local to a transaction: a statement run outside one is given
+// a transaction of its own, and the transactions GORM opens around writes
+// are set up as they begin. Statements in a transaction the repository
+// started need nothing, as Transaction, ReadSnapshot and ScopeTx set the
+// settings themselves. Row and Rows, which leave their rows open to the
+// caller, are not covered and must run in a transaction.
+func registerSettingsCallbacks(db *gorm.DB) {
+	if !isPostgres(db) {
+		return
+	}
+	db.Callback().Query().Before("gorm:query").Register("ledger:settings_query", beginSettings)
+	db.Callback().Query().After("gorm:after_query").Register("ledger:settings_query_end", endSettings)
+	db.Callback().Raw().Before("gorm:raw").Register("ledger:settings_raw", beginSettings)
+	db.Callback().Raw().After("gorm:raw").Register("ledger:settings_raw_end", endSettings)
+	db.Callback().Create().After("gorm:begin_transaction").Before("gorm:create").Register("ledger:settings_create", beginSettings)
+	db.Callback().Create().After("gorm:commit_or_rollback_transaction").Register("ledger:settings_create_end", endSettings)
+	db.Callback().Update().After("gorm:begin_transaction").Before("gorm:update").Register("ledger:settings_update", beginSettings)
+	db.Callback().Update().After("gorm:commit_or_rollback_transaction").Register("ledger:settings_update_end", endSettings)
+	db.Callback().Delete().After("gorm:begin_transaction").Before("gorm:delete").Register("ledger:settings_delete", beginSettings)
+	db.Callback().Delete().After("gorm:commit_or_rollback_transaction").Register("ledger:settings_delete_end", endSettings)
+}
+
+const settingsTxKey = "ledger:settings_tx"
+
+func beginSettings(tx *gorm.DB) {
+	if tx.Error != nil {
+		return
+	}
+	ctx := tx.Statement.Context
+	if started, _ := tx.InstanceGet("gorm:started_transaction"); started == true {
+		tx.AddError(applySettings(ctx, tx.Statement.ConnPool))
+		return
+	}
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+	beginner, ok := tx.Statement.ConnPool.(gorm.TxBeginner)
+	if !ok {
+		return
+	}
+
+	sqlTx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		tx.AddError(err)
+		return
+	}
+	tx.Statement.ConnPool = sqlTx
+	tx.InstanceSet(settingsTxKey, sqlTx)
+	if err := applySettings(ctx, sqlTx); err != nil {
+		tx.AddError(err)
+	}
+}
+
+func endSettings(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(settingsTxKey)
+	if !ok {
+		return
+	}
+	sqlTx := value.(*sql.Tx)
+	if tx.Error != nil {
+		sqlTx.Rollback()
+		return
+	}
+	tx.AddError(sqlTx.Commit())
+}
+
+// applySettings sets the row-level security settings for the work on ctx
+// in the transaction on pool.
+func applySettings(ctx context.Context, pool gorm.ConnPool) error {
+	tenantID, _ := tenant.FromContext(ctx)
+	bypass := "off"
+	if tenant.Bypassed(ctx) {
+		bypass = "on"
+	}
+	_, err := pool.ExecContext(ctx, setSettingsSQL, tenantSetting, tenantID, bypassSetting, bypass)
+	return err
+}
+
+func stampTenant(tx *gorm.DB) {
+	tenantID, ok := tenant.FromContext(tx.Statement.Context)
+	field := tenantField(tx)
+	if !ok || field == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	stamp := func(rv reflect.Value) {
+		current, zero := field.ValueOf(ctx, rv)
+		if zero {
+			if err := field.Set(ctx, rv, tenantID); err != nil {
+				tx.AddError(err)
+			}
+		} else if current != tenantID {
+			tx.AddError(ErrTenantMismatch)
+		}
+	}
+
+	switch rv := tx.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			stamp(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		stamp(rv)
+	}
+}
+
+func tenantField(tx *gorm.DB) *schema.Field {
+	if tx.Statement.Schema == nil {
+		return nil
+	}
+	return tx.Statement.Schema.LookUpField("TenantID")
+}
+
+// WithContext returns a repository whose statements run with ctx, and so
+// are scoped to the tenant on it.
+func (r *LedgerRepository) WithContext(ctx context.Context) LedgerStore {
+	return &LedgerRepository{db: r.db.WithContext(ctx)}
+}
+
+// Transaction runs fn in a database transaction in which the row-level
+// security policies see the repository's tenant.
+func (r *LedgerRepository) Transaction(fn func(tx Tx) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := setTenantSetting(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// ScopeTx returns tx scoped to tenantID, for work that only learns its
+// tenant from a row it read inside the transaction.
+func (r *LedgerRepository) ScopeTx(tx Tx, tenantID string) (Tx, error) {
+	db := gormTx(tx)
+	db = db.WithContext(tenant.WithID(db.Statement.Context, tenantID))
+	return db, setTenantSetting(db)
+}
+
+func setTenantSetting(tx *gorm.DB) error {
+	if !isPostgres(tx) {
+		return nil
+	}
+	return applySettings(tx.Statement.Context, tx.Statement.ConnPool)
+}
+
+// isPostgres reports whether db is a Postgres database. The other database
+// the repository runs on is SQLite, which has no row-level security.
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
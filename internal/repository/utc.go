@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// registerUTCCallbacks stores the times of the rows and columns a statement
+// writes in UTC. SQLite keeps times as text and compares them as text, which
+// only orders them correctly when they share a zone; the queries that filter
+// on a time convert their arguments to UTC for the same reason.
+func registerUTCCallbacks(db *gorm.DB) {
+	db.Callback().Create().Before("gorm:create").Register("ledger:utc_create", utcTimes)
+	db.Callback().Update().Before("gorm:update").Register("ledger:utc_update", utcTimes)
+}
+
+func utcTimes(tx *gorm.DB) {
+	if values, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		for column, value := range values {
+			if t, ok := value.(time.Time); ok {
+				values[column] = t.UTC()
+			}
+		}
+	}
+	if tx.Statement.Schema == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	convert := func(rv reflect.Value) {
+		for _, field := range tx.Statement.Schema.Fields {
+			value, zero := field.ValueOf(ctx, rv)
+			if zero {
+				continue
+			}
+			switch t := value.(type) {
+			case time.Time:
+				tx.AddError(field.Set(ctx, rv, t.UTC()))
+			case *time.Time:
+				utc := t.UTC()
+				tx.AddError(field.Set(ctx, rv, &utc))
+			}
+		}
+	}
+
+	switch rv := tx.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			convert(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		convert(rv)
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"ledger/internal/auth"
 	"ledger/internal/handler"
 
 	"github.com/go-chi/chi/v5"
@@ -16,13 +17,62 @@ func SetupRoutes(h *handler.LedgerHandler) *chi.Mux {
 	r.Get("/", h.HealthCheck)
 
 	r.Route("/v1", func(r chi.Router) {
+		r.Use(h.Authenticate)
 
-		r.Post("/accounts", h.CreateAccount)
-		r.Get("/accounts/{accountID}/balance", h.GetBalance)
+		accountsRead := r.With(h.RequireScope(auth.ScopeAccountsRead))
+		accountsWrite := r.With(h.RequireScope(auth.ScopeAccountsWrite))
+		transfersRead := r.With(h.RequireScope(auth.ScopeTransfersRead))
+		transfersWrite := r.With(h.RequireScope(auth.ScopeTransfersWrite))
+		transfersReverse := r.With(h.RequireScope(auth.ScopeTransfersReverse))
+		admin := r.With(h.RequireScope(auth.ScopeAdmin))
 
-		r.Post("/transactions", h.CreateTransaction)
-		r.Get("/transactions", h.ListTransactions)
-		r.Post("/transactions/{transactionID}/reverse", h.ReverseTransaction)
+		accountsWrite.Post("/accounts", h.CreateAccount)
+		accountsRead.Get("/accounts", h.ListAccounts)
+		accountsRead.Get("/accounts/{accountID}", h.GetAccount)
+		accountsWrite.Patch("/accounts/{accountID}", h.UpdateAccount)
+		accountsRead.Get("/accounts/{accountID}/balance", h.GetBalance)
+		accountsRead.Get("/accounts/{accountID}/balance-history", h.GetBalanceHistory)
+		accountsRead.Get("/accounts/{accountID}/statement", h.GetStatement)
+		accountsRead.Get("/accounts/{accountID}/audit-log", h.GetAccountAuditLog)
+		accountsWrite.Put("/accounts/{accountID}/status", h.ChangeAccountStatus)
+		accountsRead.Get("/accounts/{accountID}/status-history", h.GetAccountStatusHistory)
+
+		transfersWrite.Post("/transactions", h.CreateTransaction)
+		transfersRead.Get("/transactions", h.ListTransactions)
+		transfersReverse.Post("/transactions/{transactionID}/reverse", h.ReverseTransaction)
+
+		transfersWrite.Post("/transfers", h.CreateTransfer)
+		transfersRead.Get("/transfers/{transferID}", h.GetTransfer)
+		transfersWrite.Post("/transfers/batch", h.CreateTransferBatch)
+		transfersRead.Get("/transfers/batches/{batchID}", h.GetTransferBatch)
+
+		transfersWrite.Post("/scheduled-transfers", h.CreateScheduledTransfer)
+		transfersRead.Get("/scheduled-transfers/{scheduleID}", h.GetScheduledTransfer)
+		transfersRead.Get("/scheduled-transfers/{scheduleID}/runs", h.ListScheduledTransferRuns)
+		transfersWrite.Post("/scheduled-transfers/{scheduleID}/cancel", h.CancelScheduledTransfer)
+
+		transfersRead.Get("/journal-entries/{entryID}", h.GetJournalEntry)
+
+		admin.Get("/audit/verify", h.VerifyAuditChain)
+
+		admin.Post("/api-keys", h.CreateAPIKey)
+		admin.Get("/api-keys", h.ListAPIKeys)
+		admin.Delete("/api-keys/{keyID}", h.RevokeAPIKey)
+
+		admin.Post("/webhooks", h.CreateWebhook)
+		admin.Get("/webhooks", h.ListWebhooks)
+		admin.Get("/webhooks/{webhookID}", h.GetWebhook)
+		admin.Patch("/webhooks/{webhookID}", h.UpdateWebhook)
+		admin.Delete("/webhooks/{webhookID}", h.DeleteWebhook)
+		admin.Get("/webhooks/{webhookID}/deliveries", h.ListWebhookDeliveries)
+		admin.Post("/webhooks/deliveries/{deliveryID}/redeliver", h.RedeliverWebhook)
+
+		transfersWrite.Post("/fx-quotes", h.CreateFXQuote)
+
+		transfersWrite.Post("/holds", h.CreateHold)
+		transfersRead.Get("/holds/{holdID}", h.GetHold)
+		transfersWrite.Post("/holds/{holdID}/capture", h.CaptureHold)
+		transfersWrite.Post("/holds/{holdID}/void", h.VoidHold)
 	})
 
 	return r
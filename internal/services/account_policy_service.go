@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+var ErrInvalidPolicy = newError(KindInvalid, "invalid_policy", "an account that must never go negative cannot have an overdraft limit")
+
+type AccountPolicy struct {
+	OverdraftLimit models.Amount
+	NeverNegative  bool
+}
+
+type AccountPolicyUpdate struct {
+	OverdraftLimit *models.Amount
+	NeverNegative  *bool
+}
+
+type policyChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+func validatePolicy(currency string, policy AccountPolicy) error {
+	if policy.OverdraftLimit < 0 {
+		return ErrInvalidPolicy.withMessage("overdraft limit cannot be negative")
+	}
+	if policy.NeverNegative && policy.OverdraftLimit > 0 {
+		return ErrInvalidPolicy
+	}
+	return models.ValidateCurrencyAmount(currency, policy.OverdraftLimit)
+}
+
+// checkDebit reports whether account may be debited by amount under its
+// status and balance policy. System accounts are not limited.
+func checkDebit(account *models.Account, amount models.Amount) error {
+	if account.System {
+		return nil
+	}
+	if err := checkPosting(account, models.TransactionTypeDebit); err != nil {
+		return err
+	}
+
+	remaining := account.AvailableBalance() - amount
+	if remaining >= 0 {
+		return nil
+	}
+	if account.OverdraftLimit == 0 {
+		return ErrInsufficientFunds
+	}
+	if remaining < -account.OverdraftLimit {
+		return ErrOverdraftLimitExceeded
+	}
+	return nil
+}
+
+// checkSettlement reports whether account may be debited by amount to
+// reverse a posting or capture a hold. These settle money the ledger has
+// already let through, so they are not held to the available balance or the
+// overdraft limit and may take an account below zero, unless the account
+// must never go negative.
+func checkSettlement(account *models.Account, amount models.Amount) error {
+	if account.System {
+		return nil
+	}
+	if err := checkPosting(account, models.TransactionTypeDebit); err != nil {
+		return err
+	}
+
+	if account.NeverNegative && account.Balance-amount < 0 {
+		return ErrInsufficientFunds.withMessage("account %s must never go negative", account.ID)
+	}
+	return nil
+}
+
+func (s *LedgerService) UpdateAccountPolicy(ctx context.Context, accountID string, update AccountPolicyUpdate, reason string) (*models.Account, error) {
+	s = s.scoped(ctx)
+	var account *models.Account
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		accounts, err := s.lockAccounts(tx, accountID)
+		if err != nil {
+			return err
+		}
+		account = accounts[accountID]
+
+		if account.System {
+			return ErrSystemAccount
+		}
+		if account.Status == models.AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		policy := AccountPolicy{
+			OverdraftLimit: account.OverdraftLimit,
+			NeverNegative:  account.NeverNegative,
+		}
+		changes := make(map[string]policyChange)
+
+		if update.OverdraftLimit != nil && *update.OverdraftLimit != policy.OverdraftLimit {
+			changes["overdraft_limit"] = policyChange{From: policy.OverdraftLimit, To: *update.OverdraftLimit}
+			policy.OverdraftLimit = *update.OverdraftLimit
+		}
+		if update.NeverNegative != nil && *update.NeverNegative != policy.NeverNegative {
+			changes["never_negative"] = policyChange{From: policy.NeverNegative, To: *update.NeverNegative}
+			policy.NeverNegative = *update.NeverNegative
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := validatePolicy(account.Currency, policy); err != nil {
+			return err
+		}
+
+		if err := s.repo.UpdateAccountPolicyInTx(tx, accountID, policy.OverdraftLimit, policy.NeverNegative); err != nil {
+			return err
+		}
+		account.OverdraftLimit = policy.OverdraftLimit
+		account.NeverNegative = policy.NeverNegative
+
+		payload, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		return s.repo.CreateAuditLogInTx(tx, &models.AuditLog{
+			TenantID:   account.TenantID,
+			EntityType: "account",
+			EntityID:   accountID,
+			Action:     "policy.updated",
+			Changes:    payload,
+			Reason:     reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *LedgerService) GetAccountAuditLog(ctx context.Context, accountID string) ([]models.AuditLog, error) {
+	s = s.scoped(ctx)
+	if _, err := s.repo.GetAccountByID(accountID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return s.repo.GetAuditLogs("account", accountID)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+// Reversals and captures settle money the ledger already let through, so
+// they may take an account below zero, unless it must never go negative.
+func TestNeverNegative(t *testing.T) {
+	tests := []struct {
+		name          string
+		neverNegative bool
+		want          error
+		balance       string
+	}{
+		{"may go negative", false, nil, "-60.00"},
+		{"never negative", true, ErrInsufficientFunds, "20.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(t, memory.NewStore())
+			payer := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+			payee := openAccount(t, s, ctx, "0", AccountPolicy{})
+			account := openAccount(t, s, ctx, "0", AccountPolicy{NeverNegative: tt.neverNegative})
+
+			var credits []*models.JournalEntry
+			for i := 0; i < 2; i++ {
+				entry, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: payer.ID, ToAccountID: account.ID, Amount: amount(t, "30.00")}, nil)
+				if err != nil {
+					t.Fatalf("CreateTransaction: %v", err)
+				}
+				credits = append(credits, entry)
+			}
+			hold, err := s.CreateHold(ctx, account.ID, amount(t, "50.00"), "", time.Time{})
+			if err != nil {
+				t.Fatalf("CreateHold: %v", err)
+			}
+			if _, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: account.ID, ToAccountID: payee.ID, Amount: amount(t, "10.00")}, nil); err != nil {
+				t.Fatalf("CreateTransaction: %v", err)
+			}
+
+			// Taking back the first credit leaves 20.00 of the 50.00 held,
+			// which is no reason to refuse it.
+			if _, err := s.ReverseTransaction(ctx, credits[0].Postings[0].ID, 0, nil); err != nil {
+				t.Fatalf("ReverseTransaction: %v", err)
+			}
+
+			if _, err := s.ReverseTransaction(ctx, credits[1].Postings[0].ID, 0, nil); !errors.Is(err, tt.want) {
+				t.Errorf("reversal below zero = %v, want %v", err, tt.want)
+			}
+			if _, err := s.CaptureHold(ctx, hold.ID, payee.ID, 0, ""); !errors.Is(err, tt.want) {
+				t.Errorf("capture below zero = %v, want %v", err, tt.want)
+			}
+			wantBalances(t, s, ctx, map[string]string{account.ID: tt.balance})
+
+			// New spending stays held to the balance either way.
+			if _, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: account.ID, ToAccountID: payee.ID, Amount: amount(t, "30.00")}, nil); !errors.Is(err, ErrInsufficientFunds) {
+				t.Errorf("transfer below zero = %v, want %v", err, ErrInsufficientFunds)
+			}
+		})
+	}
+}
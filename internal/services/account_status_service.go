@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+var (
+	ErrInvalidAccountStatus   = newError(KindInvalid, "invalid_account_status", "invalid account status")
+	ErrReasonRequired         = newError(KindInvalid, "reason_required", "a reason is required to change the account status")
+	ErrAccountStatusUnchanged = newError(KindConflict, "account_status_unchanged", "account already has this status")
+	ErrAccountNotEmpty        = newError(KindConflict, "account_not_empty", "only accounts with a zero balance and no active holds can be closed")
+	ErrAccountClosed          = newError(KindRejected, "account_closed", "account is closed")
+	ErrSystemAccount          = newError(KindRejected, "system_account", "system accounts cannot be changed")
+)
+
+// checkPosting rejects postings the account status does not allow.
+func checkPosting(account *models.Account, postingType models.TransactionType) error {
+	if account.System {
+		return nil
+	}
+
+	allowed := account.Status.AllowsCredit()
+	if postingType == models.TransactionTypeDebit {
+		allowed = account.Status.AllowsDebit()
+	}
+	if allowed {
+		return nil
+	}
+
+	if account.Status == models.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+	return newError(KindRejected, "account_"+string(account.Status), fmt.Sprintf("account %s is %s", account.ID, account.Status))
+}
+
+func (s *LedgerService) ChangeAccountStatus(ctx context.Context, accountID string, status models.AccountStatus, reason string) (*models.Account, error) {
+	s = s.scoped(ctx)
+	if !status.Valid() {
+		return nil, ErrInvalidAccountStatus
+	}
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	var account *models.Account
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		accounts, err := s.lockAccounts(tx, accountID)
+		if err != nil {
+			return err
+		}
+		account = accounts[accountID]
+
+		if account.System {
+			return ErrSystemAccount
+		}
+		if account.Status == models.AccountStatusClosed {
+			return ErrAccountClosed
+		}
+		if account.Status == status {
+			return ErrAccountStatusUnchanged.withMessage("account is already %s", status)
+		}
+		if status == models.AccountStatusClosed && (account.Balance != 0 || account.HeldBalance != 0) {
+			return ErrAccountNotEmpty
+		}
+
+		change := &models.AccountStatusChange{
+			TenantID:   account.TenantID,
+			AccountID:  accountID,
+			FromStatus: account.Status,
+			ToStatus:   status,
+			Reason:     reason,
+		}
+		if err := s.repo.UpdateAccountStatusInTx(tx, accountID, status); err != nil {
+			return err
+		}
+		account.Status = status
+
+		return s.repo.CreateAccountStatusChangeInTx(tx, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *LedgerService) GetAccountStatusHistory(ctx context.Context, accountID string) ([]models.AccountStatusChange, error) {
+	s = s.scoped(ctx)
+	if _, err := s.repo.GetAccountByID(accountID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return s.repo.GetAccountStatusChanges(accountID)
+}
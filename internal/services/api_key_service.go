@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// apiKeyTouchInterval limits how often a key's last_used_at is written, so
+// that busy keys do not cost a write per request.
+const apiKeyTouchInterval = time.Minute
+
+var (
+	ErrAPIKeyNotFound = newError(KindNotFound, "api_key_not_found", "api key not found")
+	ErrInvalidAPIKey  = newError(KindUnauthenticated, "invalid_api_key", "invalid or revoked api key")
+	ErrScopeRequired  = newError(KindInvalid, "scope_required", "an api key needs at least one scope")
+	ErrUnknownScope   = newError(KindInvalid, "unknown_scope", "unknown scope")
+)
+
+func (s *LedgerService) CreateAPIKey(ctx context.Context, name string, scopes []string) (*models.APIKey, string, error) {
+	return CreateAPIKey(s.repo.WithContext(ctx), name, scopes)
+}
+
+func (s *LedgerService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.WithContext(ctx).GetAPIKeys()
+}
+
+func (s *LedgerService) RevokeAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	return RevokeAPIKey(s.repo.WithContext(ctx), keyID, s.clock.Now())
+}
+
+func (s *LedgerService) AuthenticateAPIKey(token string) (*auth.Principal, error) {
+	return AuthenticateAPIKey(s.repo, token, s.clock.Now())
+}
+
+// CreateAPIKey stores a new key with scopes, in the tenant repo is scoped
+// to, and returns it together with its token, lk_<prefix>_<secret>. Only a
+// hash of the token is kept, so it cannot be shown again. Like
+// VerifyHashChain it only needs a repository, so the CLI can issue the first
+// key.
+func CreateAPIKey(repo repository.LedgerStore, name string, scopes []string) (*models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrScopeRequired
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			return nil, "", ErrUnknownScope.withMessage("unknown scope %q", scope)
+		}
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	token := "lk_" + prefix + "_" + secret
+
+	key := &models.APIKey{
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hashAPIKey(token),
+		Scopes:  models.StringList(scopes),
+	}
+	if err := repo.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, token, nil
+}
+
+// RevokeAPIKey marks the key revoked as of now.
+func RevokeAPIKey(repo repository.LedgerStore, keyID string, now time.Time) (*models.APIKey, error) {
+	if err := repo.RevokeAPIKey(keyID, now); err != nil {
+		return nil, err
+	}
+
+	key, err := repo.GetAPIKeyByID(keyID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// AuthenticateAPIKey resolves token to the principal of an active key,
+// recording its use at now. It looks the key up across all tenants, so repo
+// must not be scoped.
+func AuthenticateAPIKey(repo repository.LedgerStore, token string, now time.Time) (*auth.Principal, error) {
+	rest, ok := strings.CutPrefix(token, "lk_")
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := repo.GetAPIKeyByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(hashAPIKey(token)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := repo.TouchAPIKey(key.ID, now); err != nil {
+			slog.Warn("Failed to record api key use", "key_id", key.ID, "error", err)
+		}
+	}
+
+	return &auth.Principal{KeyID: key.ID, TenantID: key.TenantID, Scopes: key.Scopes}, nil
+}
+
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
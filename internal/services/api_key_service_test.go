@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"ledger/internal/auth"
+	"ledger/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+// Keys are revoked and their use recorded at the time of the service's
+// clock, which also stamps the rows of the store.
+func TestAPIKeyTimes(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{now: created}
+	s := newTestService(t, memory.NewStore(memory.WithNowFunc(clock.Now)), WithClock(clock))
+
+	key, token, err := s.CreateAPIKey(ctx, "test", []string{auth.ScopeAccountsRead})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !key.CreatedAt.Equal(created) {
+		t.Errorf("key created at %s, want %s", key.CreatedAt, created)
+	}
+
+	used := created.Add(time.Hour)
+	clock.Set(used)
+	if _, err := s.AuthenticateAPIKey(token); err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	key, err = s.repo.GetAPIKeyByID(key.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKeyByID: %v", err)
+	}
+	if key.LastUsedAt == nil || !key.LastUsedAt.Equal(used) {
+		t.Errorf("key last used at %v, want %s", key.LastUsedAt, used)
+	}
+
+	revoked := used.Add(time.Hour)
+	clock.Set(revoked)
+	key, err = s.RevokeAPIKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if key.RevokedAt == nil || !key.RevokedAt.Equal(revoked) {
+		t.Errorf("key revoked at %v, want %s", key.RevokedAt, revoked)
+	}
+	if _, err := s.AuthenticateAPIKey(token); err == nil {
+		t.Error("revoked key still authenticates")
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"time"
+)
+
+const maxBalanceHistoryBuckets = 1000
+
+var ErrInvalidInterval = newError(KindInvalid, "invalid_interval", "invalid interval")
+
+const (
+	BalanceIntervalDay   = "day"
+	BalanceIntervalWeek  = "week"
+	BalanceIntervalMonth = "month"
+)
+
+// BalanceHistoryBucket holds an account's balances and posting totals for
+// the days from Start to End, inclusive.
+type BalanceHistoryBucket struct {
+	Start          time.Time
+	End            time.Time
+	OpeningBalance models.Amount
+	ClosingBalance models.Amount
+	TotalDebits    models.Amount
+	TotalCredits   models.Amount
+}
+
+// GetBalanceAsOf returns the account's ledger balance at asOf: the closing
+// balance of the last snapshot before that day plus the postings made on the
+// day up to asOf.
+func (s *LedgerService) GetBalanceAsOf(ctx context.Context, accountID string, asOf time.Time) (*Balance, error) {
+	s = s.scoped(ctx)
+	account, err := s.repo.GetAccountByID(accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	day := models.StartOfDay(asOf)
+	opening, err := s.openingBalance(accountID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	sameDay, err := s.repo.SumPostings(accountID, day, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Balance{
+		AccountID:     account.ID,
+		Currency:      account.Currency,
+		LedgerBalance: opening + sameDay,
+	}, nil
+}
+
+// GetBalanceHistory summarises the account's balance per interval bucket
+// for the days from from to to, both inclusive.
+func (s *LedgerService) GetBalanceHistory(ctx context.Context, accountID string, from, to time.Time, interval string) ([]BalanceHistoryBucket, error) {
+	s = s.scoped(ctx)
+	if _, err := s.repo.GetAccountByID(accountID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	from, to = models.StartOfDay(from), models.StartOfDay(to)
+	if to.Before(from) {
+		return nil, ErrInvalidRange
+	}
+
+	var buckets []BalanceHistoryBucket
+	for start := from; !start.After(to); {
+		end, err := bucketEnd(start, interval)
+		if err != nil {
+			return nil, err
+		}
+		if end.After(to) {
+			end = to
+		}
+		if len(buckets) == maxBalanceHistoryBuckets {
+			return nil, ErrInvalidRange.withMessage("balance history is limited to %d buckets", maxBalanceHistoryBuckets)
+		}
+		buckets = append(buckets, BalanceHistoryBucket{Start: start, End: end})
+		start = end.AddDate(0, 0, 1)
+	}
+
+	balance, err := s.openingBalance(accountID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := s.repo.GetBalanceSnapshots(accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 0
+	for i := range buckets {
+		bucket := &buckets[i]
+		bucket.OpeningBalance = balance
+		for ; next < len(snapshots) && !snapshots[next].Day.After(bucket.End); next++ {
+			bucket.TotalDebits += snapshots[next].TotalDebits
+			bucket.TotalCredits += snapshots[next].TotalCredits
+			balance = snapshots[next].ClosingBalance
+		}
+		bucket.ClosingBalance = balance
+	}
+
+	return buckets, nil
+}
+
+// openingBalance is the account's balance at the start of day.
+func (s *LedgerService) openingBalance(accountID string, day time.Time) (models.Amount, error) {
+	snapshot, err := s.repo.GetLastBalanceSnapshotBefore(accountID, day)
+	if err != nil || snapshot == nil {
+		return 0, err
+	}
+	return snapshot.ClosingBalance, nil
+}
+
+// bucketEnd returns the last day of the interval bucket that starts at
+// start. Weeks end on Sunday and months on their last day, so only the first
+// bucket of a range can be partial at its start.
+func bucketEnd(start time.Time, interval string) (time.Time, error) {
+	switch interval {
+	case BalanceIntervalDay:
+		return start, nil
+	case BalanceIntervalWeek:
+		daysToSunday := (7 - int(start.Weekday())) % 7
+		return start.AddDate(0, 0, daysToSunday), nil
+	case BalanceIntervalMonth:
+		return time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, ErrInvalidInterval.withMessage("invalid interval %q", interval)
+	}
+}
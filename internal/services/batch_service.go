@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+const batchRolledBackCode = "batch_rolled_back"
+
+var (
+	ErrTransferBatchNotFound = newError(KindNotFound, "transfer_batch_not_found", "transfer batch not found")
+	ErrEmptyBatch            = newError(KindInvalid, "empty_batch", "batch must contain at least one transfer")
+	ErrBatchTooLarge         = newError(KindInvalid, "batch_too_large", "batch has too many transfers")
+	ErrBatchLimitExceeded    = newError(KindRejected, "batch_limit_exceeded", "batch total exceeds the limit")
+)
+
+// BatchItemError reports the transfer that made a batch fail.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("transfer %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// batchItemError blames the transfer at index for err when the ledger
+// rejected it. Anything else, such as a database error, is not the
+// transfer's fault, so it is returned as is to be retried or reported.
+func batchItemError(index int, err error) error {
+	var ledgerErr *LedgerError
+	if errors.As(err, &ledgerErr) {
+		return &BatchItemError{Index: index, Err: err}
+	}
+	return err
+}
+
+// CreateTransferBatch applies reqs in a single database transaction, so
+// either every transfer is posted or none is. When a transfer is rejected the
+// batch is still recorded, as failed, with the reason against each item.
+func (s *LedgerService) CreateTransferBatch(ctx context.Context, reqs []TransferRequest, idem *Idempotency) (*models.TransferBatch, error) {
+	s = s.scoped(ctx)
+	if len(reqs) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if s.config.BatchMaxItems > 0 && len(reqs) > s.config.BatchMaxItems {
+		return nil, ErrBatchTooLarge.withMessage("batch exceeds the limit of %d transfers", s.config.BatchMaxItems)
+	}
+	createdBy := auth.KeyID(ctx)
+	for i := range reqs {
+		if err := validateTransfer(reqs[i]); err != nil {
+			return nil, batchItemError(i, err)
+		}
+		reqs[i].CreatedByKeyID = createdBy
+	}
+
+	var batch *models.TransferBatch
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		ids := make([]string, 0, 2*len(reqs))
+		for _, req := range reqs {
+			ids = append(ids, req.FromAccountID, req.ToAccountID)
+			if req.QuoteID != "" {
+				system, err := s.fxSystemAccountIDs(tx, req.QuoteID)
+				if err != nil && !errors.Is(err, ErrQuoteNotFound) {
+					return err
+				}
+				ids = append(ids, system...)
+			}
+		}
+
+		// A missing account or quote is reported against its transfer
+		// below, so only the totals check depends on the lock succeeding.
+		accounts, err := s.lockAccounts(tx, ids...)
+		if err != nil && !errors.Is(err, ErrAccountNotFound) {
+			return err
+		}
+		if err == nil {
+			if err := s.checkBatchTotals(reqs, accounts); err != nil {
+				return err
+			}
+		}
+
+		batch = &models.TransferBatch{
+			Status:    models.TransferBatchStatusCompleted,
+			ItemCount: len(reqs),
+			Transfers: make([]models.Transfer, 0, len(reqs)),
+		}
+
+		now := s.clock.Now()
+		for i, req := range reqs {
+			entry, err := s.executeTransfer(tx, req)
+			if err != nil {
+				return batchItemError(i, err)
+			}
+
+			transfer := newTransfer(req, models.TransferStatusCompleted)
+			transfer.BatchIndex = i
+			transfer.JournalEntryID = &entry.ID
+			transfer.CompletedAt = &now
+			batch.Transfers = append(batch.Transfers, *transfer)
+		}
+
+		if err := s.repo.CreateTransferBatchInTx(tx, batch); err != nil {
+			return err
+		}
+		return s.saveIdempotentResponse(tx, idem, batch)
+	})
+
+	var itemErr *BatchItemError
+	if errors.As(err, &itemErr) {
+		return s.recordFailedBatch(reqs, itemErr, idem)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+func (s *LedgerService) GetTransferBatch(ctx context.Context, batchID string) (*models.TransferBatch, error) {
+	s = s.scoped(ctx)
+	batch, err := s.repo.GetTransferBatchByID(batchID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTransferBatchNotFound
+		}
+		return nil, err
+	}
+	return batch, nil
+}
+
+// checkBatchTotals enforces the configured cap on the amount a batch moves
+// in each currency.
+func (s *LedgerService) checkBatchTotals(reqs []TransferRequest, accounts map[string]*models.Account) error {
+	if s.config.BatchMaxTotal == 0 {
+		return nil
+	}
+
+	totals := make(map[string]models.Amount)
+	for _, req := range reqs {
+		currency := accounts[req.FromAccountID].Currency
+		totals[currency] += req.Amount
+		if totals[currency] > s.config.BatchMaxTotal {
+			return ErrBatchLimitExceeded.withMessage("batch total in %s exceeds the limit of %s", currency, s.config.BatchMaxTotal)
+		}
+	}
+	return nil
+}
+
+func (s *LedgerService) recordFailedBatch(reqs []TransferRequest, itemErr *BatchItemError, idem *Idempotency) (*models.TransferBatch, error) {
+	batch := &models.TransferBatch{
+		Status:        models.TransferBatchStatusFailed,
+		ItemCount:     len(reqs),
+		FailureReason: itemErr.Error(),
+		Transfers:     make([]models.Transfer, 0, len(reqs)),
+	}
+
+	for i, req := range reqs {
+		transfer := newTransfer(req, models.TransferStatusFailed)
+		transfer.BatchIndex = i
+		if i == itemErr.Index {
+			transfer.FailureReason = itemErr.Err.Error()
+			var ledgerErr *LedgerError
+			if errors.As(itemErr.Err, &ledgerErr) {
+				transfer.FailureCode = ledgerErr.Code
+			}
+		} else {
+			transfer.FailureReason = "not applied because another transfer in the batch failed"
+			transfer.FailureCode = batchRolledBackCode
+		}
+		batch.Transfers = append(batch.Transfers, *transfer)
+	}
+
+	err := s.repo.Transaction(func(tx repository.Tx) error {
+		if err := s.repo.CreateTransferBatchInTx(tx, batch); err != nil {
+			return err
+		}
+		return s.saveIdempotentResponse(tx, idem, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
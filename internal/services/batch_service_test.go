@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/repository/memory"
+	"testing"
+)
+
+func TestCreateTransferBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	a := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+	c := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	batch, err := s.CreateTransferBatch(ctx, []TransferRequest{
+		{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "60.00")},
+		{FromAccountID: b.ID, ToAccountID: c.ID, Amount: amount(t, "50.00")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransferBatch: %v", err)
+	}
+	if batch.Status != models.TransferBatchStatusCompleted || len(batch.Transfers) != 2 {
+		t.Fatalf("batch = %s with %d transfers, want completed with 2", batch.Status, len(batch.Transfers))
+	}
+	for _, transfer := range batch.Transfers {
+		if transfer.Status != models.TransferStatusCompleted || transfer.JournalEntryID == nil {
+			t.Errorf("transfer %d = %s, want completed with a journal entry", transfer.BatchIndex, transfer.Status)
+		}
+	}
+	wantBalances(t, s, ctx, map[string]string{a.ID: "40.00", b.ID: "10.00", c.ID: "50.00"})
+
+	stored, err := s.GetTransferBatch(ctx, batch.ID)
+	if err != nil {
+		t.Fatalf("GetTransferBatch: %v", err)
+	}
+	if len(stored.Transfers) != 2 || stored.Transfers[1].BatchIndex != 1 {
+		t.Errorf("stored batch has transfers %+v", stored.Transfers)
+	}
+}
+
+// A batch is applied whole or not at all: when one transfer is rejected
+// none of the others is posted, and the batch is recorded as failed.
+func TestCreateTransferBatchIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	a := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	batch, err := s.CreateTransferBatch(ctx, []TransferRequest{
+		{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "60.00")},
+		{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "60.00")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransferBatch: %v", err)
+	}
+	if batch.Status != models.TransferBatchStatusFailed {
+		t.Fatalf("batch status = %s, want failed", batch.Status)
+	}
+	if got := batch.Transfers[0].FailureCode; got != batchRolledBackCode {
+		t.Errorf("first transfer failure code = %q, want %q", got, batchRolledBackCode)
+	}
+	if got := batch.Transfers[1].FailureCode; got != ErrInsufficientFunds.Code {
+		t.Errorf("second transfer failure code = %q, want %q", got, ErrInsufficientFunds.Code)
+	}
+	wantBalances(t, s, ctx, map[string]string{a.ID: "100.00", b.ID: "0"})
+}
+
+func TestCreateTransferBatchRejects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	a := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	if _, err := s.CreateTransferBatch(ctx, nil, nil); !errors.Is(err, ErrEmptyBatch) {
+		t.Errorf("empty batch = %v, want %v", err, ErrEmptyBatch)
+	}
+
+	_, err := s.CreateTransferBatch(ctx, []TransferRequest{
+		{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 1},
+		{FromAccountID: a.ID, ToAccountID: a.ID, Amount: 1},
+	}, nil)
+	var itemErr *BatchItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 || !errors.Is(err, ErrSameAccount) {
+		t.Errorf("invalid item = %v, want %v at index 1", err, ErrSameAccount)
+	}
+
+	s.config.BatchMaxItems = 1
+	if _, err := s.CreateTransferBatch(ctx, make([]TransferRequest, 2), nil); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("oversized batch = %v, want %v", err, ErrBatchTooLarge)
+	}
+	wantBalances(t, s, ctx, map[string]string{a.ID: "100.00", b.ID: "0"})
+}
+
+// Errors the ledger did not reject a transfer with are not pinned on it:
+// the batch is not recorded as failed and the error comes back as is.
+func TestCreateTransferBatchStoreError(t *testing.T) {
+	ctx := context.Background()
+	var fault error
+	s := newTestService(t, faultyStore{LedgerStore: memory.NewStore(), err: &fault})
+	a := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	errStore := errors.New("store unavailable")
+	fault = errStore
+	batch, err := s.CreateTransferBatch(ctx, []TransferRequest{
+		{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "10.00")},
+	}, nil)
+	var itemErr *BatchItemError
+	if batch != nil || errors.As(err, &itemErr) || !errors.Is(err, errStore) {
+		t.Fatalf("CreateTransferBatch = %v, %v, want %v alone", batch, err, errStore)
+	}
+
+	fault = nil
+	wantBalances(t, s, ctx, map[string]string{a.ID: "100.00", b.ID: "0"})
+}
+
+// faultyStore fails to read chain heads with the error in err while there
+// is one.
+type faultyStore struct {
+	repository.LedgerStore
+	err *error
+}
+
+func (s faultyStore) WithContext(ctx context.Context) repository.LedgerStore {
+	return faultyStore{LedgerStore: s.LedgerStore.WithContext(ctx), err: s.err}
+}
+
+func (s faultyStore) GetChainHeadInTx(tx repository.Tx, accountID string) (*models.Transaction, error) {
+	if *s.err != nil {
+		return nil, *s.err
+	}
+	return s.LedgerStore.GetChainHeadInTx(tx, accountID)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"time"
+)
+
+// MaxTransactionPageSize caps the postings ListTransactions returns at once.
+const MaxTransactionPageSize = 100
+
+// TransactionPage is a page of postings, newest first. NextCursor fetches
+// the older postings after it and PrevCursor the newer ones before it; each
+// is empty when there is nothing more that way.
+type TransactionPage struct {
+	Transactions []models.Transaction
+	Limit        int
+	NextCursor   string
+	PrevCursor   string
+}
+
+// transactionCursor is what a cursor token holds: the posting a page starts
+// next to and the way it goes from there. Tokens are opaque to clients.
+type transactionCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// listingStart and listingEnd are positions before the newest and after the
+// oldest of all postings.
+var (
+	listingStart = models.Transaction{CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC), ID: "ffffffff-ffff-ffff-ffff-ffffffffffff"}
+	listingEnd   = models.Transaction{CreatedAt: time.Unix(0, 0).UTC(), ID: "00000000-0000-0000-0000-000000000000"}
+)
+
+func encodeCursor(t models.Transaction, backward bool) string {
+	data, _ := json.Marshal(transactionCursor{CreatedAt: t.CreatedAt, ID: t.ID, Backward: backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*transactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor transactionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || !models.IsID(cursor.ID) || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+func (c *transactionCursor) position() *repository.TransactionCursor {
+	return &repository.TransactionCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// pageOf builds the page for the postings fetched at cursor, which asked for
+// one more than limit to learn whether the listing goes on past the page.
+func pageOf(transactions []models.Transaction, cursor *transactionCursor, limit int) *TransactionPage {
+	more := len(transactions) > limit
+	if more && cursor != nil && cursor.Backward {
+		transactions = transactions[1:]
+	} else if more {
+		transactions = transactions[:limit]
+	}
+
+	page := &TransactionPage{Transactions: transactions, Limit: limit}
+	if len(transactions) == 0 {
+		// An empty page past either end leads back to the last page before
+		// it, which includes the posting at the cursor: nothing lies beyond
+		// that posting, so the page can be taken from the end of the listing.
+		if cursor != nil {
+			if cursor.Backward {
+				page.NextCursor = encodeCursor(listingStart, false)
+			} else {
+				page.PrevCursor = encodeCursor(listingEnd, true)
+			}
+		}
+		return page
+	}
+
+	// A page reached one way always leads back the other; onward only
+	// when there was more.
+	newest, oldest := transactions[0], transactions[len(transactions)-1]
+	switch {
+	case cursor == nil:
+		if more {
+			page.NextCursor = encodeCursor(oldest, false)
+		}
+	case cursor.Backward:
+		page.NextCursor = encodeCursor(oldest, false)
+		if more {
+			page.PrevCursor = encodeCursor(newest, true)
+		}
+	default:
+		page.PrevCursor = encodeCursor(newest, true)
+		if more {
+			page.NextCursor = encodeCursor(oldest, false)
+		}
+	}
+	return page
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/repository/memory"
+	"ledger/internal/repository/storetest"
+	"ledger/internal/tenant"
+	"testing"
+	"time"
+)
+
+// Cursor pages walk the postings of an account in both directions without
+// skipping or repeating any, on every store, even where postings share
+// their creation time and while new ones are written.
+func TestListTransactionsCursor(t *testing.T) {
+	stores := []struct {
+		name string
+		open func(t *testing.T) repository.LedgerStore
+	}{
+		{"Memory", func(*testing.T) repository.LedgerStore { return memory.NewStore() }},
+		{"SQLite", storetest.NewSQLiteStore},
+		{"Postgres", storetest.NewPostgresStore},
+	}
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			testListTransactionsCursor(t, store.open(t))
+		})
+	}
+}
+
+func testListTransactionsCursor(t *testing.T, store repository.LedgerStore) {
+	const limit = 3
+	ctx := tenant.WithID(context.Background(), models.NewID())
+	created := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := &testClock{now: created}
+	s := newTestService(t, store, WithClock(clock))
+
+	// The opening balance and five transfers share a creation time, and two
+	// more come a second later: eight postings on a, in two ties.
+	a := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+	transfer := func() {
+		t.Helper()
+		if _, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "1.00")}, nil); err != nil {
+			t.Fatalf("CreateTransaction: %v", err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		transfer()
+	}
+	clock.Set(created.Add(time.Second))
+	for i := 0; i < 2; i++ {
+		transfer()
+	}
+
+	list := func(cursor string, limit int) *TransactionPage {
+		t.Helper()
+		page, err := s.ListTransactions(ctx, TransactionFilter{AccountID: a.ID}, cursor, limit)
+		if err != nil {
+			t.Fatalf("ListTransactions: %v", err)
+		}
+		return page
+	}
+
+	all := list("", MaxTransactionPageSize).Transactions
+	if len(all) != 8 {
+		t.Fatalf("listed %d postings, want 8", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		prev, next := all[i-1], all[i]
+		if next.CreatedAt.After(prev.CreatedAt) || next.CreatedAt.Equal(prev.CreatedAt) && next.ID > prev.ID {
+			t.Fatalf("postings %d and %d are out of order", i-1, i)
+		}
+	}
+
+	t.Run("forward", func(t *testing.T) {
+		var got []models.Transaction
+		page := list("", limit)
+		for pages := 1; ; pages++ {
+			got = append(got, page.Transactions...)
+			if page.NextCursor == "" {
+				break
+			}
+			if pages > len(all) {
+				t.Fatal("pages never end")
+			}
+			page = list(page.NextCursor, limit)
+		}
+		wantTransactions(t, got, all)
+	})
+
+	t.Run("backward", func(t *testing.T) {
+		page := list(encodeCursor(all[len(all)-1], false), limit)
+		var got []models.Transaction
+		for pages := 1; page.PrevCursor != ""; pages++ {
+			if pages > len(all) {
+				t.Fatal("pages never end")
+			}
+			page = list(page.PrevCursor, limit)
+			got = append(append([]models.Transaction(nil), page.Transactions...), got...)
+		}
+		wantTransactions(t, got, all)
+	})
+
+	t.Run("past either end", func(t *testing.T) {
+		older := list(encodeCursor(all[len(all)-1], false), limit)
+		if len(older.Transactions) != 0 || older.NextCursor != "" || older.PrevCursor == "" {
+			t.Fatalf("page past the oldest posting = %d postings, next %q, prev %q, want an empty page leading back", len(older.Transactions), older.NextCursor, older.PrevCursor)
+		}
+		wantTransactions(t, list(older.PrevCursor, limit).Transactions, all[len(all)-limit:])
+
+		newer := list(encodeCursor(all[0], true), limit)
+		if len(newer.Transactions) != 0 || newer.PrevCursor != "" || newer.NextCursor == "" {
+			t.Fatalf("page past the newest posting = %d postings, next %q, prev %q, want an empty page leading back", len(newer.Transactions), newer.NextCursor, newer.PrevCursor)
+		}
+		wantTransactions(t, list(newer.NextCursor, limit).Transactions, all[:limit])
+	})
+
+	// Postings written between fetches are newer than every page already
+	// handed out, so the pages onward are unchanged and the new postings
+	// show up on the way back.
+	t.Run("inserts between fetches", func(t *testing.T) {
+		first := list("", limit)
+		clock.Set(created.Add(2 * time.Second))
+		transfer()
+		transfer()
+
+		second := list(first.NextCursor, limit)
+		var got []models.Transaction
+		for page := second; ; page = list(page.NextCursor, limit) {
+			got = append(got, page.Transactions...)
+			if page.NextCursor == "" {
+				break
+			}
+		}
+		wantTransactions(t, got, all[limit:])
+
+		back := list(second.PrevCursor, limit)
+		wantTransactions(t, back.Transactions, all[:limit])
+		if back.PrevCursor == "" {
+			t.Fatal("page before the first has no way to the new postings")
+		}
+		inserted := list(back.PrevCursor, limit).Transactions
+		if len(inserted) != 2 || !inserted[0].CreatedAt.Equal(clock.Now()) || !inserted[1].CreatedAt.Equal(clock.Now()) {
+			t.Errorf("newest page = %v, want the two new postings", inserted)
+		}
+	})
+}
+
+// wantTransactions checks that got lists the postings of want, in order.
+func wantTransactions(t *testing.T, got, want []models.Transaction) {
+	t.Helper()
+	ids := func(transactions []models.Transaction) []string {
+		ids := make([]string, len(transactions))
+		for i, transaction := range transactions {
+			ids[i] = transaction.ID
+		}
+		return ids
+	}
+	gotIDs, wantIDs := ids(got), ids(want)
+	if len(gotIDs) != len(wantIDs) {
+		t.Fatalf("listed %v, want %v", gotIDs, wantIDs)
+	}
+	for i := range wantIDs {
+		if gotIDs[i] != wantIDs[i] {
+			t.Fatalf("listed %v, want %v", gotIDs, wantIDs)
+		}
+	}
+}
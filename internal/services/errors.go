@@ -0,0 +1,76 @@
+package services
+
+import "fmt"
+
+// ErrorKind classifies a LedgerError by what went wrong with the request,
+// so that transports can map every error of a kind the same way.
+type ErrorKind int
+
+const (
+	// KindInvalid means the request itself is malformed or out of range.
+	KindInvalid ErrorKind = iota + 1
+	// KindNotFound means a resource the request names does not exist.
+	KindNotFound
+	// KindConflict means the request clashes with the resource's state.
+	KindConflict
+	// KindRejected means the request is well formed but a ledger rule,
+	// such as an account's balance policy, does not allow it.
+	KindRejected
+	// KindUnauthenticated means the caller could not be identified.
+	KindUnauthenticated
+	// KindUnavailable means the ledger cannot take the request right now.
+	KindUnavailable
+)
+
+// LedgerError is a rejection with a stable, machine-readable code that
+// clients can branch on instead of the message. Errors with the same code
+// match under errors.Is, whatever their message.
+type LedgerError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+func newError(kind ErrorKind, code, message string) *LedgerError {
+	return &LedgerError{Kind: kind, Code: code, Message: message}
+}
+
+func (e *LedgerError) Error() string {
+	return e.Message
+}
+
+func (e *LedgerError) Is(target error) bool {
+	t, ok := target.(*LedgerError)
+	return ok && t.Code == e.Code
+}
+
+// withMessage returns e with a more specific message.
+func (e *LedgerError) withMessage(format string, args ...interface{}) *LedgerError {
+	return &LedgerError{Kind: e.Kind, Code: e.Code, Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	ErrAccountNotFound        = newError(KindNotFound, "account_not_found", "account not found")
+	ErrTransactionNotFound    = newError(KindNotFound, "transaction_not_found", "transaction not found")
+	ErrJournalEntryNotFound   = newError(KindNotFound, "journal_entry_not_found", "journal entry not found")
+	ErrInvalidAmount          = newError(KindInvalid, "invalid_amount", "amount must be greater than zero")
+	ErrSameAccount            = newError(KindInvalid, "same_account", "cannot transfer to the same account")
+	ErrInvalidRange           = newError(KindInvalid, "invalid_range", "from must not be after to")
+	ErrInvalidCursor          = newError(KindInvalid, "invalid_cursor", "cursor is not valid")
+	ErrInsufficientFunds      = newError(KindRejected, "insufficient_funds", "insufficient balance")
+	ErrOverdraftLimitExceeded = newError(KindRejected, "overdraft_limit_exceeded", "overdraft limit exceeded")
+	ErrCurrencyMismatch       = newError(KindRejected, "currency_mismatch", "currency mismatch: transfers between accounts in different currencies need an FX quote")
+	ErrCrossTenant            = newError(KindRejected, "cross_tenant", "accounts belong to different tenants")
+	ErrShuttingDown           = newError(KindUnavailable, "shutting_down", "service is shutting down")
+)
+
+// validateTransfer checks the parts of req that need no database.
+func validateTransfer(req TransferRequest) error {
+	if req.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return ErrSameAccount
+	}
+	return nil
+}
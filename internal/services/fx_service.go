@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"math/big"
+)
+
+const rateDecimals = 12
+
+var (
+	ErrQuoteNotFound  = newError(KindNotFound, "quote_not_found", "fx quote not found")
+	ErrSameCurrency   = newError(KindInvalid, "same_currency", "fx quote currencies must differ")
+	ErrQuoteExpired   = newError(KindRejected, "quote_expired", "fx quote has expired")
+	ErrQuoteUsed      = newError(KindConflict, "quote_used", "fx quote has already been used")
+	ErrQuoteMismatch  = newError(KindRejected, "quote_mismatch", "fx quote does not match the account currencies")
+	ErrAmountTooSmall = newError(KindRejected, "amount_too_small", "amount is too small to convert")
+)
+
+func (s *LedgerService) CreateFXQuote(ctx context.Context, fromCurrency, toCurrency string) (*models.FXQuote, error) {
+	s = s.scoped(ctx)
+	if _, err := models.CurrencyMinorUnits(fromCurrency); err != nil {
+		return nil, err
+	}
+	if _, err := models.CurrencyMinorUnits(toCurrency); err != nil {
+		return nil, err
+	}
+	if fromCurrency == toCurrency {
+		return nil, ErrSameCurrency
+	}
+
+	mid, err := s.rates.Rate(fromCurrency, toCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := new(big.Rat).Mul(mid, big.NewRat(int64(10000-s.config.FXSpreadBps), 10000))
+
+	now := s.clock.Now()
+	quote := &models.FXQuote{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		MidRate:      mid.FloatString(rateDecimals),
+		Rate:         rate.FloatString(rateDecimals),
+		ExpiresAt:    now.Add(s.config.FXQuoteTTL),
+		CreatedAt:    now,
+	}
+	if err := s.repo.CreateFXQuote(quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+func (s *LedgerService) ConvertWithQuote(quote *models.FXQuote, amount models.Amount) (models.Amount, error) {
+	return convertAmount(amount, quote.Rate, quote.ToCurrency)
+}
+
+// convertAmount multiplies amount by rate and rounds half up to the minor
+// units of currency.
+func convertAmount(amount models.Amount, rate string, currency string) (models.Amount, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok {
+		return 0, errors.New("invalid exchange rate")
+	}
+
+	units, err := models.CurrencyMinorUnits(currency)
+	if err != nil {
+		return 0, err
+	}
+
+	step := int64(1)
+	for i := units; i < models.AmountScale; i++ {
+		step *= 10
+	}
+
+	value := new(big.Rat).Mul(big.NewRat(int64(amount), step), r)
+	num := new(big.Int).Mul(value.Num(), big.NewInt(2))
+	num.Add(num, value.Denom())
+	rounded := num.Quo(num, new(big.Int).Mul(value.Denom(), big.NewInt(2)))
+	rounded.Mul(rounded, big.NewInt(step))
+
+	if !rounded.IsInt64() || models.Amount(rounded.Int64()) > models.MaxAmount {
+		return 0, models.ErrAmountOverflow
+	}
+	return models.Amount(rounded.Int64()), nil
+}
+
+// fxSystemAccountIDs returns the IDs of the FX position accounts of the
+// quote's currencies and of the gain/loss account of its target currency,
+// creating them on first use, so that postFXTransfer's caller can lock them
+// together with the transfer's own accounts.
+func (s *LedgerService) fxSystemAccountIDs(tx repository.Tx, quoteID string) ([]string, error) {
+	quote, err := s.repo.GetFXQuoteByIDForUpdate(tx, quoteID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, err
+	}
+
+	system := []struct{ kind, currency string }{
+		{models.SystemAccountFXPosition, quote.FromCurrency},
+		{models.SystemAccountFXPosition, quote.ToCurrency},
+		{models.SystemAccountFXGainLoss, quote.ToCurrency},
+	}
+	ids := make([]string, 0, len(system))
+	for _, a := range system {
+		account, err := s.repo.GetSystemAccount(tx, a.kind, a.currency)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, account.ID)
+	}
+	return ids, nil
+}
+
+// systemAccount finds the system account of the given kind and currency
+// among accounts.
+func systemAccount(accounts map[string]*models.Account, kind, currency string) *models.Account {
+	name := models.SystemAccountName(kind, currency)
+	for _, account := range accounts {
+		if account.System && account.OwnerName == name {
+			return account
+		}
+	}
+	return nil
+}
+
+// postFXTransfer moves req.Amount out of the source account into the FX
+// position account of its currency and pays the converted amount out of the
+// position account of the target currency. The spread between the mid rate
+// and the quoted rate is booked to the FX gain/loss account. accounts must
+// hold the system accounts named by fxSystemAccountIDs, locked.
+func (s *LedgerService) postFXTransfer(tx repository.Tx, req TransferRequest, accounts map[string]*models.Account) (*models.JournalEntry, error) {
+	quote, err := s.repo.GetFXQuoteByIDForUpdate(tx, req.QuoteID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if quote.UsedAt != nil {
+		return nil, ErrQuoteUsed
+	}
+	if now.After(quote.ExpiresAt) {
+		return nil, ErrQuoteExpired
+	}
+
+	from, to := accounts[req.FromAccountID], accounts[req.ToAccountID]
+	if quote.FromCurrency != from.Currency || quote.ToCurrency != to.Currency {
+		return nil, ErrQuoteMismatch
+	}
+
+	credited, err := convertAmount(req.Amount, quote.Rate, quote.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := convertAmount(req.Amount, quote.MidRate, quote.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if credited <= 0 {
+		return nil, ErrAmountTooSmall
+	}
+
+	fromPosition := systemAccount(accounts, models.SystemAccountFXPosition, from.Currency)
+	toPosition := systemAccount(accounts, models.SystemAccountFXPosition, to.Currency)
+
+	postings := []models.Transaction{
+		{AccountID: from.ID, Type: models.TransactionTypeDebit, Amount: req.Amount},
+		{AccountID: fromPosition.ID, Type: models.TransactionTypeCredit, Amount: req.Amount},
+		{AccountID: toPosition.ID, Type: models.TransactionTypeDebit, Amount: converted},
+		{AccountID: to.ID, Type: models.TransactionTypeCredit, Amount: credited},
+	}
+
+	if gain := converted - credited; gain != 0 {
+		gainLoss := systemAccount(accounts, models.SystemAccountFXGainLoss, to.Currency)
+		gainPosting := models.Transaction{AccountID: gainLoss.ID, Type: models.TransactionTypeCredit, Amount: gain}
+		if gain < 0 {
+			gainPosting.Type = models.TransactionTypeDebit
+			gainPosting.Amount = -gain
+		}
+		postings = append(postings, gainPosting)
+	}
+
+	entry := &models.JournalEntry{
+		Type:           models.JournalEntryTypeFXTransfer,
+		Description:    req.Description,
+		CreatedByKeyID: req.CreatedByKeyID,
+		Postings:       postings,
+	}
+	if err := s.postJournalEntry(tx, entry, accounts); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkFXQuoteUsedInTx(tx, quote.ID, entry.ID, now); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/repository/memory"
+	"sync"
+	"testing"
+)
+
+// FX transfers in opposite directions lock the position accounts of both
+// currencies, and in the same order as their own accounts, so that none of
+// them deadlocks and the spread is booked once per transfer.
+func TestFXTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	s.config.FXSpreadBps = 100
+
+	const transfers = 8
+	reqs := make([]TransferRequest, transfers)
+	want := make(map[string]string)
+	for i := range reqs {
+		eur, err := s.CreateAccount(ctx, "test", "EUR", amount(t, "100.00"), AccountPolicy{}, nil)
+		if err != nil {
+			t.Fatalf("CreateAccount: %v", err)
+		}
+		usd := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+
+		// 10.00 EUR at 1.10 less 1% is 10.89 USD, and 10.00 USD at 1/1.10
+		// less 1% is 9.00 EUR.
+		req := TransferRequest{FromAccountID: eur.ID, ToAccountID: usd.ID, Amount: amount(t, "10.00")}
+		from, to := "EUR", "USD"
+		want[eur.ID], want[usd.ID] = "90.00", "110.89"
+		if i%2 == 1 {
+			req.FromAccountID, req.ToAccountID = usd.ID, eur.ID
+			from, to = to, from
+			want[usd.ID], want[eur.ID] = "90.00", "109.00"
+		}
+		quote, err := s.CreateFXQuote(ctx, from, to)
+		if err != nil {
+			t.Fatalf("CreateFXQuote: %v", err)
+		}
+		req.QuoteID = quote.ID
+		reqs[i] = req
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, transfers)
+	for _, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry, err := s.CreateTransaction(ctx, req, nil)
+			if err == nil && len(entry.Postings) != 5 {
+				t.Errorf("entry has %d postings, want 5 with the spread", len(entry.Postings))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateTransaction: %v", err)
+		}
+	}
+	wantBalances(t, s, ctx, want)
+
+	if _, err := s.CreateTransaction(ctx, reqs[0], nil); !errors.Is(err, ErrQuoteUsed) {
+		t.Fatalf("reusing a quote = %v, want %v", err, ErrQuoteUsed)
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"time"
+)
+
+const holdSweepBatchSize = 100
+
+var (
+	ErrHoldNotFound       = newError(KindNotFound, "hold_not_found", "hold not found")
+	ErrInvalidExpiry      = newError(KindInvalid, "invalid_expiry", "hold expiry must be in the future")
+	ErrHoldNotActive      = newError(KindConflict, "hold_not_active", "hold is not active")
+	ErrHoldExpired        = newError(KindConflict, "hold_expired", "hold has expired")
+	ErrCaptureExceedsHold = newError(KindRejected, "capture_exceeds_hold", "capture amount exceeds the held amount")
+)
+
+func (s *LedgerService) CreateHold(ctx context.Context, accountID string, amount models.Amount, description string, expiresAt time.Time) (*models.Hold, error) {
+	s = s.scoped(ctx)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	now := s.clock.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(s.config.HoldDefaultTTL)
+	}
+	if !expiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+
+	var hold *models.Hold
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		accounts, err := s.lockAccounts(tx, accountID)
+		if err != nil {
+			return err
+		}
+		account := accounts[accountID]
+
+		if err := models.ValidateCurrencyAmount(account.Currency, amount); err != nil {
+			return err
+		}
+
+		if err := checkDebit(account, amount); err != nil {
+			return err
+		}
+
+		hold = &models.Hold{
+			AccountID:   accountID,
+			Amount:      amount,
+			Currency:    account.Currency,
+			Status:      models.HoldStatusActive,
+			Description: description,
+			ExpiresAt:   expiresAt,
+		}
+		if err := s.repo.CreateHoldInTx(tx, hold); err != nil {
+			return err
+		}
+
+		account.HeldBalance += amount
+		return s.repo.UpdateAccountHeldBalanceInTx(tx, accountID, account.HeldBalance)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (s *LedgerService) GetHold(ctx context.Context, holdID string) (*models.Hold, error) {
+	s = s.scoped(ctx)
+	hold, err := s.repo.GetHoldByID(holdID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CaptureHold turns an active hold into a transfer of amount to toAccountID,
+// releasing whatever part of the hold is not captured. A zero amount
+// captures the full hold.
+func (s *LedgerService) CaptureHold(ctx context.Context, holdID, toAccountID string, amount models.Amount, description string) (*models.Hold, error) {
+	s = s.scoped(ctx)
+	if amount < 0 {
+		return nil, ErrInvalidAmount.withMessage("capture amount cannot be negative")
+	}
+
+	// A hold never changes account, so this can be checked before locking.
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if toAccountID == hold.AccountID {
+		return nil, ErrSameAccount
+	}
+
+	err = s.retryTransaction(func(tx repository.Tx) error {
+		var accounts map[string]*models.Account
+		var err error
+		hold, accounts, err = s.lockHold(tx, holdID, toAccountID)
+		if err != nil {
+			return err
+		}
+
+		if s.clock.Now().After(hold.ExpiresAt) {
+			return ErrHoldExpired
+		}
+
+		// The closure may run again on a retry, so the defaults go into
+		// copies of the arguments.
+		captured, memo := amount, description
+		if captured == 0 {
+			captured = hold.Amount
+		}
+		if captured > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		if err := s.releaseHold(tx, hold, accounts[hold.AccountID]); err != nil {
+			return err
+		}
+
+		if err := checkSettlement(accounts[hold.AccountID], captured); err != nil {
+			return err
+		}
+
+		if memo == "" {
+			memo = "Capture of hold " + hold.ID
+		}
+		entry, err := s.postTransfer(tx, TransferRequest{
+			FromAccountID:  hold.AccountID,
+			ToAccountID:    toAccountID,
+			Amount:         captured,
+			Description:    memo,
+			CreatedByKeyID: auth.KeyID(ctx),
+		}, accounts)
+		if err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldStatusCaptured
+		hold.CapturedAmount = captured
+		hold.JournalEntryID = &entry.ID
+		return s.repo.UpdateHoldInTx(tx, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (s *LedgerService) VoidHold(ctx context.Context, holdID string) (*models.Hold, error) {
+	s = s.scoped(ctx)
+	var hold *models.Hold
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		var accounts map[string]*models.Account
+		var err error
+		hold, accounts, err = s.lockHold(tx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.releaseHold(tx, hold, accounts[hold.AccountID]); err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldStatusVoided
+		return s.repo.UpdateHoldInTx(tx, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// lockHold locks the held account and any other accounts the caller needs,
+// then the hold itself, and checks the hold is still active.
+func (s *LedgerService) lockHold(tx repository.Tx, holdID string, accountIDs ...string) (*models.Hold, map[string]*models.Account, error) {
+	hold, err := s.repo.GetHoldByID(holdID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrHoldNotFound
+		}
+		return nil, nil, err
+	}
+
+	accounts, err := s.lockAccounts(tx, append(accountIDs, hold.AccountID)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hold, err = s.repo.GetHoldByIDForUpdate(tx, holdID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hold.Status != models.HoldStatusActive {
+		return nil, nil, ErrHoldNotActive
+	}
+
+	return hold, accounts, nil
+}
+
+func (s *LedgerService) releaseHold(tx repository.Tx, hold *models.Hold, account *models.Account) error {
+	account.HeldBalance -= hold.Amount
+	return s.repo.UpdateAccountHeldBalanceInTx(tx, account.ID, account.HeldBalance)
+}
+
+func (s *LedgerService) expireHolds() {
+	defer s.workerPool.Done()
+
+	ticker := time.NewTicker(s.config.HoldSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			now := s.clock.Now()
+			holds, err := s.repo.GetExpiredHolds(now, holdSweepBatchSize)
+			if err != nil {
+				slog.Error("Failed to load expired holds", "error", err)
+				continue
+			}
+
+			for _, h := range holds {
+				if err := s.expireHold(h.ID, now); err != nil && !errors.Is(err, ErrHoldNotActive) {
+					slog.Error("Failed to expire hold", "hold_id", h.ID, "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *LedgerService) expireHold(holdID string, now time.Time) error {
+	return s.retryTransaction(func(tx repository.Tx) error {
+		hold, accounts, err := s.lockHold(tx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if now.Before(hold.ExpiresAt) {
+			return nil
+		}
+
+		if err := s.releaseHold(tx, hold, accounts[hold.AccountID]); err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldStatusExpired
+		return s.repo.UpdateHoldInTx(tx, hold)
+	})
+}
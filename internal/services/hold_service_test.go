@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestHoldCapture(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	hold, err := s.CreateHold(ctx, from.ID, amount(t, "60.00"), "", time.Time{})
+	if err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+
+	balance, err := s.GetBalance(ctx, from.ID)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance.LedgerBalance != amount(t, "100.00") || balance.AvailableBalance != amount(t, "40.00") {
+		t.Fatalf("balance = %s available %s, want 100.00 available 40.00", balance.LedgerBalance, balance.AvailableBalance)
+	}
+
+	if _, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "40.01")}, nil); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("spending held funds = %v, want %v", err, ErrInsufficientFunds)
+	}
+	if _, err := s.CaptureHold(ctx, hold.ID, from.ID, 0, ""); !errors.Is(err, ErrSameAccount) {
+		t.Fatalf("capturing into the held account = %v, want %v", err, ErrSameAccount)
+	}
+	if _, err := s.CaptureHold(ctx, hold.ID, to.ID, amount(t, "60.01"), ""); !errors.Is(err, ErrCaptureExceedsHold) {
+		t.Fatalf("capturing more than held = %v, want %v", err, ErrCaptureExceedsHold)
+	}
+
+	captured, err := s.CaptureHold(ctx, hold.ID, to.ID, amount(t, "25.00"), "")
+	if err != nil {
+		t.Fatalf("CaptureHold: %v", err)
+	}
+	if captured.Status != models.HoldStatusCaptured || captured.CapturedAmount != amount(t, "25.00") || captured.JournalEntryID == nil {
+		t.Fatalf("hold = %s capturing %s, want captured 25.00 with a journal entry", captured.Status, captured.CapturedAmount)
+	}
+
+	// The part of the hold that was not captured is released.
+	balance, err = s.GetBalance(ctx, from.ID)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance.LedgerBalance != amount(t, "75.00") || balance.AvailableBalance != amount(t, "75.00") {
+		t.Fatalf("balance = %s available %s, want 75.00 available 75.00", balance.LedgerBalance, balance.AvailableBalance)
+	}
+	wantBalances(t, s, ctx, map[string]string{to.ID: "25.00"})
+
+	if _, err := s.CaptureHold(ctx, hold.ID, to.ID, 0, ""); !errors.Is(err, ErrHoldNotActive) {
+		t.Fatalf("capturing twice = %v, want %v", err, ErrHoldNotActive)
+	}
+}
+
+func TestHoldVoidAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	account := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+
+	if _, err := s.CreateHold(ctx, account.ID, amount(t, "100.01"), "", time.Time{}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("holding more than available = %v, want %v", err, ErrInsufficientFunds)
+	}
+	if _, err := s.CreateHold(ctx, account.ID, amount(t, "1.00"), "", time.Now().Add(-time.Minute)); !errors.Is(err, ErrInvalidExpiry) {
+		t.Fatalf("holding with a past expiry = %v, want %v", err, ErrInvalidExpiry)
+	}
+
+	voided, err := s.CreateHold(ctx, account.ID, amount(t, "30.00"), "", time.Time{})
+	if err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+	expiring, err := s.CreateHold(ctx, account.ID, amount(t, "20.00"), "", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+
+	if hold, err := s.VoidHold(ctx, voided.ID); err != nil || hold.Status != models.HoldStatusVoided {
+		t.Fatalf("VoidHold = %v, %v, want a voided hold", hold, err)
+	}
+	if _, err := s.VoidHold(ctx, voided.ID); !errors.Is(err, ErrHoldNotActive) {
+		t.Fatalf("voiding twice = %v, want %v", err, ErrHoldNotActive)
+	}
+
+	if err := s.expireHold(expiring.ID, time.Now()); err != nil {
+		t.Fatalf("expireHold before expiry: %v", err)
+	}
+	if hold, _ := s.GetHold(ctx, expiring.ID); hold.Status != models.HoldStatusActive {
+		t.Fatalf("hold expired early: %s", hold.Status)
+	}
+	if err := s.expireHold(expiring.ID, time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatalf("expireHold: %v", err)
+	}
+	if hold, _ := s.GetHold(ctx, expiring.ID); hold.Status != models.HoldStatusExpired {
+		t.Fatalf("hold status = %s, want expired", hold.Status)
+	}
+
+	account, err = s.GetAccount(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account.Balance != amount(t, "100.00") || account.HeldBalance != 0 {
+		t.Fatalf("balance = %s held %s, want 100.00 held 0", account.Balance, account.HeldBalance)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"time"
+)
+
+const idempotencyPurgeInterval = time.Hour
+
+var (
+	ErrIdempotencyKeyReused   = newError(KindRejected, "idempotency_key_reused", "idempotency key was already used with a different request")
+	ErrIdempotencyKeyConflict = newError(KindConflict, "idempotency_key_conflict", "a request with this idempotency key is already being processed")
+)
+
+// Idempotency carries the client supplied key for a mutating call. Response
+// renders the HTTP status and body for the call's result so it can be stored
+// in the same database transaction and replayed on retries.
+type Idempotency struct {
+	Key         string
+	Fingerprint string
+	Response    func(result interface{}) (int, interface{})
+}
+
+func (s *LedgerService) FindIdempotentResponse(ctx context.Context, key, fingerprint string) (*models.IdempotencyKey, error) {
+	s = s.scoped(ctx)
+	record, err := s.repo.GetIdempotencyKey(key)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !record.ExpiresAt.After(s.clock.Now()) {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	return record, nil
+}
+
+func (s *LedgerService) saveIdempotentResponse(tx repository.Tx, idem *Idempotency, result interface{}) error {
+	if idem == nil {
+		return nil
+	}
+
+	status, body := idem.Response(result)
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	saved, err := s.repo.SaveIdempotencyKeyInTx(tx, &models.IdempotencyKey{
+		Key:          idem.Key,
+		Fingerprint:  idem.Fingerprint,
+		StatusCode:   status,
+		ResponseBody: payload,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.config.IdempotencyTTL),
+	})
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrIdempotencyKeyConflict
+	}
+	return nil
+}
+
+func (s *LedgerService) purgeIdempotencyKeys() {
+	defer s.workerPool.Done()
+
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			now := s.clock.Now()
+			deleted, err := s.repo.DeleteExpiredIdempotencyKeys(now)
+			if err != nil {
+				slog.Error("Failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Purged expired idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"net/http"
+	"testing"
+)
+
+func testIdempotency(key, fingerprint string) *Idempotency {
+	return &Idempotency{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Response: func(result interface{}) (int, interface{}) {
+			return http.StatusCreated, result
+		},
+	}
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+	req := TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "10.00")}
+
+	if record, err := s.FindIdempotentResponse(ctx, "key-1", "fp"); record != nil || err != nil {
+		t.Fatalf("FindIdempotentResponse before use = %v, %v, want nothing", record, err)
+	}
+
+	entry, err := s.CreateTransaction(ctx, req, testIdempotency("key-1", "fp"))
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	record, err := s.FindIdempotentResponse(ctx, "key-1", "fp")
+	if err != nil || record == nil {
+		t.Fatalf("FindIdempotentResponse = %v, %v, want the stored response", record, err)
+	}
+	if record.StatusCode != http.StatusCreated {
+		t.Errorf("stored status = %d, want %d", record.StatusCode, http.StatusCreated)
+	}
+	var replayed models.JournalEntry
+	if err := json.Unmarshal(record.ResponseBody, &replayed); err != nil || replayed.ID != entry.ID {
+		t.Errorf("stored response is for %q (%v), want %q", replayed.ID, err, entry.ID)
+	}
+
+	// The same key with another request is refused, which the API reports
+	// as 422.
+	_, err = s.FindIdempotentResponse(ctx, "key-1", "other")
+	if !errors.Is(err, ErrIdempotencyKeyReused) || ErrIdempotencyKeyReused.Kind != KindRejected {
+		t.Fatalf("FindIdempotentResponse with another fingerprint = %v, want %v", err, ErrIdempotencyKeyReused)
+	}
+
+	// A request racing past the lookup cannot post twice.
+	if _, err := s.CreateTransaction(ctx, req, testIdempotency("key-1", "fp")); !errors.Is(err, ErrIdempotencyKeyConflict) {
+		t.Fatalf("CreateTransaction with a used key = %v, want %v", err, ErrIdempotencyKeyConflict)
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "90.00", to.ID: "10.00"})
+}
+
+// A rejected request stores no response, so the key can be used again.
+func TestIdempotencyKeyOfRejectedRequest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "5.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	req := TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "10.00")}
+	if _, err := s.CreateTransaction(ctx, req, testIdempotency("key-2", "fp")); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("CreateTransaction = %v, want %v", err, ErrInsufficientFunds)
+	}
+	if record, err := s.FindIdempotentResponse(ctx, "key-2", "fp"); record != nil || err != nil {
+		t.Fatalf("FindIdempotentResponse = %v, %v, want nothing stored", record, err)
+	}
+
+	req.Amount = amount(t, "5.00")
+	if _, err := s.CreateTransaction(ctx, req, testIdempotency("key-2", "fp")); err != nil {
+		t.Fatalf("CreateTransaction reusing the key: %v", err)
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "0", to.ID: "5.00"})
+}
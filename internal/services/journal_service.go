@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"sort"
+	"time"
+)
+
+func validatePostings(postings []models.Transaction) error {
+	if len(postings) < 2 {
+		return errors.New("journal entry needs at least two postings")
+	}
+
+	totals := make(map[string]models.Amount)
+	for _, p := range postings {
+		if p.Amount <= 0 {
+			return errors.New("posting amount must be greater than zero")
+		}
+		if p.Type != models.TransactionTypeDebit && p.Type != models.TransactionTypeCredit {
+			return fmt.Errorf("invalid posting type %q", p.Type)
+		}
+		if err := models.ValidateCurrencyAmount(p.Currency, p.Amount); err != nil {
+			return err
+		}
+		totals[p.Currency] += p.SignedAmount()
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("journal entry is unbalanced by %s %s", total, currency)
+		}
+	}
+	return nil
+}
+
+// lockAccounts takes row locks on the given accounts in ID order, the same
+// order every writer uses, so concurrent entries cannot deadlock.
+func (s *LedgerService) lockAccounts(tx repository.Tx, ids ...string) (map[string]*models.Account, error) {
+	sorted := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Strings(sorted)
+
+	accounts := make(map[string]*models.Account, len(sorted))
+	for _, id := range sorted {
+		account, err := s.repo.GetAccountByIDForUpdate(tx, id)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrAccountNotFound
+			}
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// postJournalEntry writes a balanced entry and applies its postings to the
+// balances of accounts, which must already be locked by the caller. Postings
+// take the currency and tenant of their account, must balance per currency,
+// must stay within one tenant and must be allowed by the status of their
+// account. Each posting is appended to its
+// account's hash chain, stamped with the entry's API key, and the accounts'
+// daily balance snapshots are updated in the same transaction.
+func (s *LedgerService) postJournalEntry(tx repository.Tx, entry *models.JournalEntry, accounts map[string]*models.Account) error {
+	// Postgres keeps microseconds; truncating here keeps the hashes computed
+	// below valid for the rows as they are read back.
+	now := s.clock.Now().UTC().Truncate(time.Microsecond)
+	entry.ID = models.NewID()
+	entry.CreatedAt = now
+
+	deltas := make(map[string]models.Amount, len(entry.Postings))
+	debits := make(map[string]models.Amount, len(entry.Postings))
+	credits := make(map[string]models.Amount, len(entry.Postings))
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		account, ok := accounts[posting.AccountID]
+		if !ok {
+			return fmt.Errorf("account %s is not locked", posting.AccountID)
+		}
+		if err := checkPosting(account, posting.Type); err != nil {
+			return err
+		}
+		if i == 0 {
+			entry.TenantID = account.TenantID
+		} else if account.TenantID != entry.TenantID {
+			return ErrCrossTenant
+		}
+		posting.TenantID = account.TenantID
+		posting.Currency = account.Currency
+		posting.Description = entry.Description
+		posting.CreatedByKeyID = entry.CreatedByKeyID
+		posting.CreatedAt = now
+		deltas[posting.AccountID] += posting.SignedAmount()
+		if posting.Type == models.TransactionTypeDebit {
+			debits[posting.AccountID] += posting.Amount
+		} else {
+			credits[posting.AccountID] += posting.Amount
+		}
+	}
+
+	if err := validatePostings(entry.Postings); err != nil {
+		return err
+	}
+
+	if err := s.chainPostings(tx, entry); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateJournalEntryInTx(tx, entry); err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		account := accounts[id]
+		account.Balance += deltas[id]
+		if err := s.repo.UpdateAccountBalanceInTx(tx, id, account.Balance); err != nil {
+			return err
+		}
+
+		if err := s.repo.UpsertBalanceSnapshotInTx(tx, &models.BalanceSnapshot{
+			TenantID:       account.TenantID,
+			AccountID:      id,
+			Day:            models.StartOfDay(now),
+			OpeningBalance: account.Balance - deltas[id],
+			ClosingBalance: account.Balance,
+			TotalDebits:    debits[id],
+			TotalCredits:   credits[id],
+			UpdatedAt:      now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *LedgerService) GetJournalEntry(ctx context.Context, id string) (*models.JournalEntry, error) {
+	s = s.scoped(ctx)
+	entry, err := s.repo.GetJournalEntryByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrJournalEntryNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.attachReversals(entry.Postings); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// chainPostings numbers the entry's postings within their accounts and links
+// each to the hash of the account's previous posting. The accounts are
+// locked, so their chain heads cannot move underneath us.
+func (s *LedgerService) chainPostings(tx repository.Tx, entry *models.JournalEntry) error {
+	heads := make(map[string]*models.Transaction)
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+
+		head, ok := heads[posting.AccountID]
+		if !ok {
+			var err error
+			if head, err = s.repo.GetChainHeadInTx(tx, posting.AccountID); err != nil {
+				return err
+			}
+		}
+
+		posting.ID = models.NewID()
+		posting.JournalEntryID = entry.ID
+		posting.Sequence = 1
+		if head != nil {
+			posting.Sequence = head.Sequence + 1
+			posting.PrevHash = head.Hash
+		}
+		posting.Hash = posting.ChainHash()
+		heads[posting.AccountID] = posting
+	}
+	return nil
+}
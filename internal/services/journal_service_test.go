@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// A long random sequence of transfers, including rejected ones, never
+// creates or destroys money: the accounts keep their total to the cent, the
+// ledger as a whole sums to zero and every balance is the sum of its
+// postings.
+func TestTransfersConserveTotal(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+
+	seed := time.Now().UnixNano()
+	t.Logf("seed %d", seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	var accounts []*models.Account
+	var total models.Amount
+	for i := 0; i < 8; i++ {
+		initial := models.Amount(rng.Int63n(100000))
+		policy := AccountPolicy{}
+		if i%3 == 0 {
+			policy.OverdraftLimit = models.Amount(rng.Int63n(5000))
+		}
+		account, err := s.CreateAccount(ctx, "test", "USD", initial, policy, nil)
+		if err != nil {
+			t.Fatalf("CreateAccount: %v", err)
+		}
+		accounts = append(accounts, account)
+		total += initial
+	}
+
+	const transfers = 1000
+	posted := 0
+	for i := 0; i < transfers; i++ {
+		from, to := rng.Intn(len(accounts)), rng.Intn(len(accounts)-1)
+		if to >= from {
+			to++
+		}
+		req := TransferRequest{
+			FromAccountID: accounts[from].ID,
+			ToAccountID:   accounts[to].ID,
+			Amount:        models.Amount(1 + rng.Int63n(20000)),
+		}
+		_, err := s.CreateTransaction(ctx, req, nil)
+		switch {
+		case err == nil:
+			posted++
+		case errors.Is(err, ErrInsufficientFunds), errors.Is(err, ErrOverdraftLimitExceeded):
+		default:
+			t.Fatalf("transfer %d: %v", i, err)
+		}
+	}
+	if posted == 0 {
+		t.Fatal("no transfer was posted")
+	}
+
+	var sum models.Amount
+	for _, account := range accounts {
+		balance := balanceOf(t, s, ctx, account.ID)
+		if balance < -account.OverdraftLimit {
+			t.Errorf("account %s is at %s, past its overdraft limit of %s", account.ID, balance, account.OverdraftLimit)
+		}
+		postings, err := s.repo.SumPostings(account.ID, time.Time{}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("SumPostings: %v", err)
+		}
+		if postings != balance {
+			t.Errorf("account %s has a balance of %s but its postings sum to %s", account.ID, balance, postings)
+		}
+		sum += balance
+	}
+	if sum != total {
+		t.Fatalf("accounts sum to %s after %d transfers, want %s", sum, posted, total)
+	}
+
+	all, err := s.repo.GetAccountsForVerification()
+	if err != nil {
+		t.Fatalf("GetAccountsForVerification: %v", err)
+	}
+	var ledger models.Amount
+	for _, account := range all {
+		ledger += account.Balance
+	}
+	if ledger != 0 {
+		t.Fatalf("ledger sums to %s, want 0", ledger)
+	}
+}
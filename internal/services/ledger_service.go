@@ -3,43 +3,115 @@ package services
 import (
 	"context"
 	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/config"
 	"ledger/internal/models"
 	"ledger/internal/repository"
+	"ledger/internal/tenant"
+	"log/slog"
 	"sync"
-
-	"gorm.io/gorm"
+	"time"
 )
 
-const NumWorkers = 10
-
-type TransactionJob struct {
+type TransferRequest struct {
 	FromAccountID string
 	ToAccountID   string
-	Amount        float64
+	Amount        models.Amount
 	Description   string
-	ResultChan    chan error
+	QuoteID       string
+	// CreatedByKeyID is the API key the transfer is made with, if any.
+	CreatedByKeyID *string
+	// TenantID is the tenant the transfer is made in; the workers run it
+	// scoped to that tenant.
+	TenantID string
+}
+
+// TransactionFilter narrows ListTransactions to an account, a posting type
+// and a creation time range, From inclusive and To exclusive. Zero fields do
+// not narrow it.
+type TransactionFilter struct {
+	AccountID string
+	Type      models.TransactionType
+	From      time.Time
+	To        time.Time
+}
+
+// TransactionJob is either a TransferRequest to execute directly or, when
+// TransferID is set, a persisted pending transfer to settle.
+type TransactionJob struct {
+	TransferRequest
+	TransferID  string
+	Idempotency *Idempotency
+	ResultChan  chan TransactionResult
+}
+
+type TransactionResult struct {
+	Entry    *models.JournalEntry
+	Transfer *models.Transfer
+	Err      error
+}
+
+type Balance struct {
+	AccountID        string
+	Currency         string
+	LedgerBalance    models.Amount
+	AvailableBalance models.Amount
 }
 
 type LedgerService struct {
-	repo       *repository.LedgerRepository
-	db         *gorm.DB
-	mu         sync.Mutex
+	repo       repository.LedgerStore
+	config     *config.LedgerConfig
+	rates      RateProvider
+	clock      Clock
 	jobQueue   chan *TransactionJob
 	workerPool *sync.WaitGroup
 	ctx        context.Context
 	cancel     context.CancelFunc
+
+	queuedTransfers *sync.Map
+}
+
+// Clock tells the service the current time, so tests can control it.
+type Clock interface {
+	Now() time.Time
 }
 
-func NewLedgerService(repo *repository.LedgerRepository, db *gorm.DB) *LedgerService {
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option configures a LedgerService.
+type Option func(*LedgerService)
+
+// WithClock makes the service read the time from clock rather than the
+// system clock.
+func WithClock(clock Clock) Option {
+	return func(s *LedgerService) {
+		s.clock = clock
+	}
+}
+
+func NewLedgerService(repo repository.LedgerStore, cfg *config.LedgerConfig, rates RateProvider, opts ...Option) *LedgerService {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Work that is not done for a caller, the background jobs' and API key
+	// lookups, spans every tenant. Callers' work is scoped to their tenant.
 	service := &LedgerService{
-		repo:       repo,
-		db:         db,
-		jobQueue:   make(chan *TransactionJob, 100),
+		repo:       repo.WithContext(tenant.WithBypass(context.Background())),
+		config:     cfg,
+		rates:      rates,
+		clock:      systemClock{},
+		jobQueue:   make(chan *TransactionJob, cfg.QueueSize),
 		workerPool: &sync.WaitGroup{},
 		ctx:        ctx,
 		cancel:     cancel,
+
+		queuedTransfers: &sync.Map{},
+	}
+	for _, opt := range opts {
+		opt(service)
 	}
 
 	service.startWorkers()
@@ -48,10 +120,43 @@ func NewLedgerService(repo *repository.LedgerRepository, db *gorm.DB) *LedgerSer
 }
 
 func (s *LedgerService) startWorkers() {
-	for i := 0; i < NumWorkers; i++ {
+	workers := s.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
 		s.workerPool.Add(1)
 		go s.worker(i)
 	}
+
+	if !s.config.BackgroundJobs {
+		return
+	}
+	s.workerPool.Add(5)
+	go s.purgeIdempotencyKeys()
+	go s.expireHolds()
+	go s.recoverTransfers()
+	go s.runScheduler()
+	go s.dispatchWebhooks()
+}
+
+// scoped returns a copy of s whose repository runs with ctx, and so only
+// sees the tenant of ctx. Methods serving a caller start by replacing their
+// receiver with it; the copy shares the workers and queues of s.
+func (s *LedgerService) scoped(ctx context.Context) *LedgerService {
+	scoped := *s
+	scoped.repo = s.repo.WithContext(ctx)
+	return &scoped
+}
+
+// forTenant is scoped for work that carries its tenant ID rather than a
+// caller's context.
+func (s *LedgerService) forTenant(tenantID string) *LedgerService {
+	if tenantID == "" {
+		return s
+	}
+	return s.scoped(tenant.WithID(s.ctx, tenantID))
 }
 
 func (s *LedgerService) worker(id int) {
@@ -61,197 +166,317 @@ func (s *LedgerService) worker(id int) {
 		select {
 		case <-s.ctx.Done():
 			return
-		case job, ok := <-s.jobQueue:
-			if !ok {
-				return
+		case job := <-s.jobQueue:
+			var result TransactionResult
+			if job.TransferID != "" {
+				result.Transfer, result.Err = s.processTransfer(job.TransferID, job.Idempotency)
+				if result.Err != nil {
+					slog.Error("Failed to process transfer", "transfer_id", job.TransferID, "error", result.Err)
+				}
+			} else {
+				result.Entry, result.Err = s.forTenant(job.TenantID).processTransaction(job.TransferRequest, job.Idempotency)
 			}
 
-			err := s.processTransaction(job.FromAccountID, job.ToAccountID, job.Amount, job.Description)
-			job.ResultChan <- err
-			close(job.ResultChan)
+			if job.ResultChan != nil {
+				job.ResultChan <- result
+				close(job.ResultChan)
+			}
 		}
 	}
 }
 
 func (s *LedgerService) Shutdown() {
 	s.cancel()
-	close(s.jobQueue)
 	s.workerPool.Wait()
 }
 
-func (s *LedgerService) CreateAccount(ownerName string, initialBalance float64) (*models.Account, error) {
+func (s *LedgerService) CreateAccount(ctx context.Context, ownerName, currency string, initialBalance models.Amount, policy AccountPolicy, idem *Idempotency) (*models.Account, error) {
+	s = s.scoped(ctx)
 	if initialBalance < 0 {
-		return nil, errors.New("initial balance cannot be negative")
+		return nil, ErrInvalidAmount.withMessage("initial balance cannot be negative")
 	}
 
-	account, err := s.repo.CreateAccount(ownerName, initialBalance)
-	if err != nil {
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if err := models.ValidateCurrencyAmount(currency, initialBalance); err != nil {
+		return nil, err
+	}
+	if err := validatePolicy(currency, policy); err != nil {
 		return nil, err
 	}
 
-	if initialBalance > 0 {
-		_, err = s.repo.CreateTransaction(
-			account.ID,
-			models.TransactionTypeCredit,
-			initialBalance,
-			"Initial balance",
-		)
-		if err != nil {
-			return nil, err
+	var account *models.Account
+	createdBy := auth.KeyID(ctx)
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		account = &models.Account{
+			OwnerName:      ownerName,
+			Currency:       currency,
+			OverdraftLimit: policy.OverdraftLimit,
+			NeverNegative:  policy.NeverNegative,
+		}
+		if err := s.repo.CreateAccountInTx(tx, account); err != nil {
+			return err
+		}
+
+		if initialBalance > 0 {
+			if err := s.postOpeningBalance(tx, account, initialBalance, createdBy); err != nil {
+				return err
+			}
+		}
+
+		if err := s.publishEvent(tx, models.EventAccountCreated, account.ID, account); err != nil {
+			return err
 		}
+
+		return s.saveIdempotentResponse(tx, idem, account)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return account, nil
 }
 
-func (s *LedgerService) GetBalance(accountID string) (float64, error) {
+func (s *LedgerService) postOpeningBalance(tx repository.Tx, account *models.Account, amount models.Amount, createdBy *string) error {
+	funding, err := s.repo.GetSystemAccountForUpdate(tx, models.SystemAccountFunding, account.Currency)
+	if err != nil {
+		return err
+	}
+
+	accounts := map[string]*models.Account{
+		account.ID: account,
+		funding.ID: funding,
+	}
+	return s.postJournalEntry(tx, &models.JournalEntry{
+		Type:           models.JournalEntryTypeOpeningBalance,
+		Description:    "Initial balance",
+		CreatedByKeyID: createdBy,
+		Postings: []models.Transaction{
+			{AccountID: funding.ID, Type: models.TransactionTypeDebit, Amount: amount},
+			{AccountID: account.ID, Type: models.TransactionTypeCredit, Amount: amount},
+		},
+	}, accounts)
+}
+
+func (s *LedgerService) GetAccount(ctx context.Context, accountID string) (*models.Account, error) {
+	s = s.scoped(ctx)
 	account, err := s.repo.GetAccountByID(accountID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, errors.New("account not found")
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
 		}
-		return 0, err
+		return nil, err
 	}
-	return account.Balance, nil
+	return account, nil
+}
+
+// MaxAccountPageSize caps the accounts ListAccounts returns at once.
+const MaxAccountPageSize = 100
+
+// ListAccounts returns a page of the customer accounts, oldest first.
+func (s *LedgerService) ListAccounts(ctx context.Context, limit, offset int) ([]models.Account, error) {
+	s = s.scoped(ctx)
+	limit = min(max(limit, 1), MaxAccountPageSize)
+	return s.repo.GetAccounts(limit, max(offset, 0))
 }
 
-func (s *LedgerService) CreateTransaction(fromAccountID, toAccountID string, amount float64, description string) error {
-	if amount <= 0 {
-		return errors.New("amount must be greater than zero")
+func (s *LedgerService) GetBalance(ctx context.Context, accountID string) (*Balance, error) {
+	s = s.scoped(ctx)
+	account, err := s.repo.GetAccountByID(accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return &Balance{
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		LedgerBalance:    account.Balance,
+		AvailableBalance: account.AvailableBalance(),
+	}, nil
+}
+
+func (s *LedgerService) CreateTransaction(ctx context.Context, req TransferRequest, idem *Idempotency) (*models.JournalEntry, error) {
+	s = s.scoped(ctx)
+	if err := validateTransfer(req); err != nil {
+		return nil, err
 	}
+	req.CreatedByKeyID = auth.KeyID(ctx)
+	req.TenantID, _ = tenant.FromContext(ctx)
 
 	job := &TransactionJob{
-		FromAccountID: fromAccountID,
-		ToAccountID:   toAccountID,
-		Amount:        amount,
-		Description:   description,
-		ResultChan:    make(chan error, 1),
+		TransferRequest: req,
+		Idempotency:     idem,
+		ResultChan:      make(chan TransactionResult, 1),
 	}
 
+	timer := time.NewTimer(s.config.TransferTimeout)
+	defer timer.Stop()
+
 	select {
 	case s.jobQueue <- job:
+	case <-timer.C:
+		return nil, ErrTransferQueueFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, ErrShuttingDown
+	}
 
-		return <-job.ResultChan
+	select {
+	case result := <-job.ResultChan:
+		return result.Entry, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	case <-s.ctx.Done():
-		return errors.New("service is shutting down")
+		// A worker may have the job still. Once the workers have stopped,
+		// its result is there if it ran at all.
+		s.workerPool.Wait()
+		select {
+		case result := <-job.ResultChan:
+			return result.Entry, result.Err
+		default:
+			return nil, ErrShuttingDown
+		}
 	}
 }
 
-func (s *LedgerService) processTransaction(fromAccountID, toAccountID string, amount float64, description string) error {
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var fromAccount, toAccount *models.Account
+func (s *LedgerService) processTransaction(req TransferRequest, idem *Idempotency) (*models.JournalEntry, error) {
+	var entry *models.JournalEntry
+	err := s.retryTransaction(func(tx repository.Tx) error {
 		var err error
+		entry, err = s.executeTransfer(tx, req)
+		if err != nil {
+			return err
+		}
 
-		if fromAccountID < toAccountID {
-			fromAccount, err = s.repo.GetAccountByIDForUpdate(tx, fromAccountID)
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("from account not found")
-				}
-				return err
-			}
-
-			toAccount, err = s.repo.GetAccountByIDForUpdate(tx, toAccountID)
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("to account not found")
-				}
-				return err
-			}
-		} else {
-			toAccount, err = s.repo.GetAccountByIDForUpdate(tx, toAccountID)
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("to account not found")
-				}
-				return err
-			}
+		return s.saveIdempotentResponse(tx, idem, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			fromAccount, err = s.repo.GetAccountByIDForUpdate(tx, fromAccountID)
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.New("from account not found")
-				}
-				return err
-			}
-		}
+	return entry, nil
+}
 
-		if fromAccount.Balance < amount {
-			return errors.New("insufficient balance")
-		}
+// executeTransfer locks the transfer's accounts in ID order, checks the transfer
+// against their status and policies and posts it inside tx, together with
+// its transfer.completed event.
+func (s *LedgerService) executeTransfer(tx repository.Tx, req TransferRequest) (*models.JournalEntry, error) {
+	fromAccountID, amount := req.FromAccountID, req.Amount
 
-		_, err = s.repo.CreateTransactionInTx(tx, fromAccountID, models.TransactionTypeDebit, amount, description)
-		if err != nil {
-			return err
-		}
+	accounts, err := s.lockTransferAccounts(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	fromAccount := accounts[fromAccountID]
 
-		if err := s.repo.UpdateAccountBalanceInTx(tx, fromAccountID, fromAccount.Balance-amount); err != nil {
-			return err
-		}
+	if err := models.ValidateCurrencyAmount(fromAccount.Currency, amount); err != nil {
+		return nil, err
+	}
 
-		_, err = s.repo.CreateTransactionInTx(tx, toAccountID, models.TransactionTypeCredit, amount, description)
-		if err != nil {
-			return err
-		}
+	if err := checkDebit(fromAccount, amount); err != nil {
+		return nil, err
+	}
 
-		if err := s.repo.UpdateAccountBalanceInTx(tx, toAccountID, toAccount.Balance+amount); err != nil {
-			return err
-		}
+	var entry *models.JournalEntry
+	if req.QuoteID != "" {
+		entry, err = s.postFXTransfer(tx, req, accounts)
+	} else {
+		entry, err = s.postTransfer(tx, req, accounts)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	if err := s.publishEvent(tx, models.EventTransferCompleted, entry.ID, transferCompletedEvent(req, entry)); err != nil {
+		return nil, err
+	}
+	return entry, nil
 }
 
-func (s *LedgerService) ListTransactions(accountID string, limit, offset int) ([]models.Transaction, error) {
-	if accountID != "" {
-		return s.repo.GetTransactionsByAccountID(accountID, limit, offset)
+// lockTransferAccounts locks the accounts of req, and the FX system
+// accounts its quote posts to, in the order lockAccounts takes them.
+func (s *LedgerService) lockTransferAccounts(tx repository.Tx, req TransferRequest) (map[string]*models.Account, error) {
+	ids := []string{req.FromAccountID, req.ToAccountID}
+	if req.QuoteID != "" {
+		system, err := s.fxSystemAccountIDs(tx, req.QuoteID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, system...)
 	}
-	return s.repo.GetAllTransactions(limit, offset)
-}
 
-func (s *LedgerService) ReverseTransaction(transactionID string) error {
-	tx, err := s.repo.GetTransactionByID(transactionID)
+	accounts, err := s.lockAccounts(tx, ids...)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("transaction not found")
+		if errors.Is(err, ErrAccountNotFound) {
+			if _, err := s.repo.GetAccountByID(req.FromAccountID); errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrAccountNotFound.withMessage("from account not found")
+			}
+			return nil, ErrAccountNotFound.withMessage("to account not found")
 		}
-		return err
+		return nil, err
 	}
+	return accounts, nil
+}
 
-	account, err := s.repo.GetAccountByID(tx.AccountID)
-	if err != nil {
-		return err
+func (s *LedgerService) postTransfer(tx repository.Tx, req TransferRequest, accounts map[string]*models.Account) (*models.JournalEntry, error) {
+	if accounts[req.FromAccountID].Currency != accounts[req.ToAccountID].Currency {
+		return nil, ErrCurrencyMismatch
 	}
 
-	var newBalance float64
-	var reverseType models.TransactionType
+	entry := &models.JournalEntry{
+		Type:           models.JournalEntryTypeTransfer,
+		Description:    req.Description,
+		CreatedByKeyID: req.CreatedByKeyID,
+		Postings: []models.Transaction{
+			{AccountID: req.FromAccountID, Type: models.TransactionTypeDebit, Amount: req.Amount},
+			{AccountID: req.ToAccountID, Type: models.TransactionTypeCredit, Amount: req.Amount},
+		},
+	}
+	if err := s.postJournalEntry(tx, entry, accounts); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
 
-	if tx.Type == models.TransactionTypeDebit {
-		newBalance = account.Balance + tx.Amount
-		reverseType = models.TransactionTypeCredit
-	} else if tx.Type == models.TransactionTypeCredit {
-		if account.Balance < tx.Amount {
-			return errors.New("insufficient balance to reverse")
+// ListTransactions returns a page of the postings matching filter, newest
+// first, from the page cursor leads to or else the first. Postings created
+// at the same time are ordered by ID, so that pages neither skip nor repeat
+// postings while new ones are written.
+func (s *LedgerService) ListTransactions(ctx context.Context, filter TransactionFilter, cursor string, limit int) (*TransactionPage, error) {
+	s = s.scoped(ctx)
+	limit = min(max(limit, 1), MaxTransactionPageSize)
+
+	query := repository.TransactionFilter{
+		AccountID: filter.AccountID,
+		Type:      filter.Type,
+		From:      filter.From,
+		To:        filter.To,
+	}
+	var position *transactionCursor
+	if cursor != "" {
+		var err error
+		if position, err = decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+		if position.Backward {
+			query.Before = position.position()
+		} else {
+			query.After = position.position()
 		}
-		newBalance = account.Balance - tx.Amount
-		reverseType = models.TransactionTypeDebit
-	} else {
-		return errors.New("cannot reverse a reversal transaction")
 	}
 
-	_, err = s.repo.CreateTransaction(
-		tx.AccountID,
-		reverseType,
-		tx.Amount,
-		"Reversal of transaction "+transactionID,
-	)
+	transactions, err := s.repo.GetTransactions(query, limit+1)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return s.repo.UpdateAccountBalance(tx.AccountID, newBalance)
+	page := pageOf(transactions, position, limit)
+	if err := s.attachReversals(page.Transactions); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
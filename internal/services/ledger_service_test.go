@@ -0,0 +1,463 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/config"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/repository/memory"
+	"ledger/internal/repository/storetest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfig() *config.LedgerConfig {
+	return &config.LedgerConfig{
+		Workers:             4,
+		QueueSize:           100,
+		IdempotencyTTL:      time.Hour,
+		FXQuoteTTL:          time.Minute,
+		HoldDefaultTTL:      time.Hour,
+		TransferTimeout:     5 * time.Second,
+		BatchMaxItems:       100,
+		ScheduleCatchUp:     models.CatchUpLatest,
+		ScheduleMissedAfter: 5 * time.Minute,
+	}
+}
+
+// newTestService returns a service over store with the background jobs
+// off, shut down when the test ends.
+func newTestService(t testing.TB, store repository.LedgerStore, opts ...Option) *LedgerService {
+	t.Helper()
+	rates, err := NewStaticRateProvider(map[string]string{"EUR/USD": "1.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewLedgerService(store, testConfig(), rates, opts...)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func openAccount(t testing.TB, s *LedgerService, ctx context.Context, balance string, policy AccountPolicy) *models.Account {
+	t.Helper()
+	account, err := s.CreateAccount(ctx, "test", "USD", amount(t, balance), policy, nil)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	return account
+}
+
+func amount(t testing.TB, s string) models.Amount {
+	t.Helper()
+	a, err := models.ParseAmount(s)
+	if err != nil {
+		t.Fatalf("ParseAmount(%q): %v", s, err)
+	}
+	return a
+}
+
+func balanceOf(t testing.TB, s *LedgerService, ctx context.Context, accountID string) models.Amount {
+	t.Helper()
+	account, err := s.GetAccount(ctx, accountID)
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	return account.Balance
+}
+
+func wantBalances(t testing.TB, s *LedgerService, ctx context.Context, want map[string]string) {
+	t.Helper()
+	for id, balance := range want {
+		if got := balanceOf(t, s, ctx, id); got != amount(t, balance) {
+			t.Errorf("balance of %s = %s, want %s", id, got, balance)
+		}
+	}
+}
+
+func TestCreateTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	entry, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "30.25")}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	if entry.Type != models.JournalEntryTypeTransfer || len(entry.Postings) != 2 {
+		t.Fatalf("entry = %s with %d postings, want a transfer with 2", entry.Type, len(entry.Postings))
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "69.75", to.ID: "30.25"})
+
+	stored, err := s.GetJournalEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetJournalEntry: %v", err)
+	}
+	if len(stored.Postings) != 2 {
+		t.Errorf("stored entry has %d postings, want 2", len(stored.Postings))
+	}
+}
+
+func TestCreateTransactionRejects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "10.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+	overdraft := openAccount(t, s, ctx, "0", AccountPolicy{OverdraftLimit: amount(t, "5.00")})
+
+	tests := []struct {
+		name string
+		req  TransferRequest
+		want error
+	}{
+		{"zero amount", TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID}, ErrInvalidAmount},
+		{"same account", TransferRequest{FromAccountID: from.ID, ToAccountID: from.ID, Amount: 1}, ErrSameAccount},
+		{"unknown from account", TransferRequest{FromAccountID: models.NewID(), ToAccountID: to.ID, Amount: 1}, ErrAccountNotFound},
+		{"unknown to account", TransferRequest{FromAccountID: from.ID, ToAccountID: models.NewID(), Amount: 1}, ErrAccountNotFound},
+		{"insufficient funds", TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "10.01")}, ErrInsufficientFunds},
+		{"overdraft exceeded", TransferRequest{FromAccountID: overdraft.ID, ToAccountID: to.ID, Amount: amount(t, "5.01")}, ErrOverdraftLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CreateTransaction(ctx, tt.req, nil); !errors.Is(err, tt.want) {
+				t.Fatalf("CreateTransaction = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "10.00", to.ID: "0", overdraft.ID: "0"})
+
+	if _, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: overdraft.ID, ToAccountID: to.ID, Amount: amount(t, "5.00")}, nil); err != nil {
+		t.Fatalf("transfer within the overdraft limit: %v", err)
+	}
+	wantBalances(t, s, ctx, map[string]string{overdraft.ID: "-5.00", to.ID: "5.00"})
+}
+
+func TestSubmitTransfer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "20.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	transfer, err := s.SubmitTransfer(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "5.00")}, false, nil)
+	if err != nil {
+		t.Fatalf("SubmitTransfer: %v", err)
+	}
+	if transfer.Status != models.TransferStatusCompleted || transfer.JournalEntryID == nil {
+		t.Fatalf("transfer status = %s, want completed with a journal entry", transfer.Status)
+	}
+
+	failed, err := s.SubmitTransfer(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "50.00")}, false, nil)
+	if err != nil {
+		t.Fatalf("SubmitTransfer: %v", err)
+	}
+	if failed.Status != models.TransferStatusFailed || failed.FailureCode != ErrInsufficientFunds.Code {
+		t.Fatalf("transfer = %s (%s), want failed with %s", failed.Status, failed.FailureCode, ErrInsufficientFunds.Code)
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "15.00", to.ID: "5.00"})
+}
+
+// Transfers in opposite directions between the same accounts lock them in
+// the same order, so none of them deadlocks or is lost.
+func TestConcurrentOpposingTransfers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	a := openAccount(t, s, ctx, "1000.00", AccountPolicy{})
+	b := openAccount(t, s, ctx, "1000.00", AccountPolicy{})
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds)
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "3.00")}, nil)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: b.ID, ToAccountID: a.ID, Amount: amount(t, "1.00")}, nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateTransaction: %v", err)
+		}
+	}
+	wantBalances(t, s, ctx, map[string]string{a.ID: "900.00", b.ID: "1100.00"})
+}
+
+// BenchmarkTransfers measures transfer throughput as the worker pool grows,
+// on SQLite and on the memory store. Each worker's share of the transfers
+// moves money between an account pair of its own, so that the pool and the
+// store are measured rather than contention on the same rows.
+func BenchmarkTransfers(b *testing.B) {
+	stores := []struct {
+		name string
+		open func(b *testing.B) repository.LedgerStore
+	}{
+		{"SQLite", func(b *testing.B) repository.LedgerStore {
+			return repository.NewLedgerRepository(storetest.OpenSQLite(b))
+		}},
+		{"Memory", func(*testing.B) repository.LedgerStore { return memory.NewStore() }},
+	}
+	for _, store := range stores {
+		for _, workers := range []int{1, 4, 16} {
+			b.Run(fmt.Sprintf("%s/workers=%d", store.name, workers), func(b *testing.B) {
+				benchmarkTransfers(b, store.open(b), workers)
+			})
+		}
+	}
+}
+
+func benchmarkTransfers(b *testing.B, store repository.LedgerStore, workers int) {
+	ctx := context.Background()
+	rates, err := NewStaticRateProvider(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Workers = workers
+	s := NewLedgerService(store, cfg, rates)
+	b.Cleanup(s.Shutdown)
+
+	reqs := make([]TransferRequest, workers)
+	for i := range reqs {
+		from := openAccount(b, s, ctx, "1000000.00", AccountPolicy{})
+		to := openAccount(b, s, ctx, "0", AccountPolicy{})
+		reqs[i] = TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(b, "0.01")}
+	}
+
+	b.ResetTimer()
+	var started atomic.Int64
+	var wg sync.WaitGroup
+	for _, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for started.Add(1) <= int64(b.N) {
+				if _, err := s.CreateTransaction(ctx, req, nil); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Transactions that lock the same accounts in opposite orders deadlock in
+// the store; lockAccounts sorts the IDs so that they queue instead.
+func TestLockAccountsOrder(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	s := newTestService(t, store)
+	a := openAccount(t, s, ctx, "0", AccountPolicy{})
+	b := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	t.Run("unsorted locks deadlock", func(t *testing.T) {
+		locked, proceed := make(chan struct{}), make(chan struct{})
+		done := make(chan error, 1)
+		err := store.Transaction(func(tx repository.Tx) error {
+			if _, err := store.GetAccountByIDForUpdate(tx, a.ID); err != nil {
+				return err
+			}
+			go func() {
+				done <- store.Transaction(func(tx repository.Tx) error {
+					if _, err := store.GetAccountByIDForUpdate(tx, b.ID); err != nil {
+						return err
+					}
+					close(locked)
+					<-proceed
+					_, err := store.GetAccountByIDForUpdate(tx, a.ID)
+					return err
+				})
+			}()
+			<-locked
+			close(proceed)
+			// Let the other transaction queue on a before taking b.
+			time.Sleep(50 * time.Millisecond)
+			_, err := store.GetAccountByIDForUpdate(tx, b.ID)
+			return err
+		})
+		otherErr := <-done
+		if !errors.Is(err, repository.ErrDeadlock) && !errors.Is(otherErr, repository.ErrDeadlock) {
+			t.Fatalf("got %v and %v, want one of them to be a deadlock", err, otherErr)
+		}
+		if !repository.IsRetryable(repository.ErrDeadlock) {
+			t.Fatal("a deadlock is not retryable")
+		}
+	})
+
+	t.Run("sorted locks queue", func(t *testing.T) {
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		for _, ids := range [][]string{{a.ID, b.ID}, {b.ID, a.ID}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs <- store.Transaction(func(tx repository.Tx) error {
+					_, err := s.lockAccounts(tx, ids...)
+					return err
+				})
+			}()
+		}
+		close(start)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("lockAccounts: %v", err)
+			}
+		}
+	})
+}
+
+func TestRetryTransaction(t *testing.T) {
+	s := newTestService(t, memory.NewStore())
+
+	attempts := 0
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		attempts++
+		if attempts < 3 {
+			return repository.ErrDeadlock
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("retryTransaction = %v after %d attempts, want success after 3", err, attempts)
+	}
+
+	attempts = 0
+	err = s.retryTransaction(func(tx repository.Tx) error {
+		attempts++
+		return ErrInsufficientFunds
+	})
+	if !errors.Is(err, ErrInsufficientFunds) || attempts != 1 {
+		t.Fatalf("retryTransaction = %v after %d attempts, want %v after 1", err, attempts, ErrInsufficientFunds)
+	}
+
+	attempts = 0
+	err = s.retryTransaction(func(tx repository.Tx) error {
+		attempts++
+		return repository.ErrDeadlock
+	})
+	if !errors.Is(err, repository.ErrDeadlock) || attempts != maxTransactionAttempts {
+		t.Fatalf("retryTransaction = %v after %d attempts, want a deadlock after %d", err, attempts, maxTransactionAttempts)
+	}
+}
+
+// holdAccountLock keeps the row of accountID locked in a transaction of its
+// own until the returned function is called or the test ends.
+func holdAccountLock(t *testing.T, store repository.LedgerStore, accountID string) (release func()) {
+	t.Helper()
+	locked, unlock := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Transaction(func(tx repository.Tx) error {
+			if _, err := store.GetAccountByIDForUpdate(tx, accountID); err != nil {
+				return err
+			}
+			close(locked)
+			<-unlock
+			return nil
+		})
+	}()
+	select {
+	case <-locked:
+	case err := <-done:
+		t.Fatalf("locking account: %v", err)
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			close(unlock)
+			if err := <-done; err != nil {
+				t.Errorf("locking account: %v", err)
+			}
+		})
+	}
+	t.Cleanup(release)
+	return release
+}
+
+// A caller of CreateTransaction stops waiting for its transfer when its
+// request is cancelled or the service shuts down, whether the transfer is
+// still queued or a worker has it.
+func TestCreateTransactionStopsWaiting(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	rates, err := NewStaticRateProvider(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Workers = 1
+	s := NewLedgerService(store, cfg, rates)
+	t.Cleanup(s.Shutdown)
+
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+	req := TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "1.00")}
+
+	// Every transfer debits the locked account, so the one worker blocks on
+	// whichever it takes and the others stay queued.
+	release := holdAccountLock(t, store, from.ID)
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := s.CreateTransaction(ctx, req, nil)
+			results <- err
+		}()
+	}
+
+	cancelled, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	cancelledResult := make(chan error, 1)
+	go func() {
+		_, err := s.CreateTransaction(cancelled, req, nil)
+		cancelledResult <- err
+	}()
+	select {
+	case err := <-cancelledResult:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("CreateTransaction with its request cancelled = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CreateTransaction still waiting after its request was cancelled")
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		s.Shutdown()
+		close(shutdown)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release()
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-results:
+			if err != nil && !errors.Is(err, ErrShuttingDown) {
+				t.Errorf("CreateTransaction during shutdown = %v, want its result or %v", err, ErrShuttingDown)
+			}
+		case <-timeout:
+			t.Fatal("CreateTransaction still waiting after the service shut down")
+		}
+	}
+	select {
+	case <-shutdown:
+	case <-timeout:
+		t.Fatal("Shutdown still waiting for the workers")
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"encoding/json"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+// publishEvent writes a domain event to the outbox inside tx, so the event
+// exists exactly when the change it describes commits.
+func (s *LedgerService) publishEvent(tx repository.Tx, eventType, entityID string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.CreateOutboxEventInTx(tx, &models.OutboxEvent{
+		Type:     eventType,
+		EntityID: entityID,
+		Payload:  payload,
+	})
+}
+
+func transferCompletedEvent(req TransferRequest, entry *models.JournalEntry) map[string]interface{} {
+	event := map[string]interface{}{
+		"journal_entry_id": entry.ID,
+		"from_account_id":  req.FromAccountID,
+		"to_account_id":    req.ToAccountID,
+		"amount":           req.Amount,
+		"currency":         entry.Postings[0].Currency,
+		"description":      req.Description,
+		"postings":         entry.Postings,
+	}
+	if req.QuoteID != "" {
+		event["quote_id"] = req.QuoteID
+	}
+	return event
+}
+
+func transactionReversedEvent(transactionID string, reversal *models.JournalEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"transaction_id":   transactionID,
+		"journal_entry_id": reversal.ID,
+		"reversal_of":      reversal.ReversalOfID,
+		"postings":         reversal.Postings,
+	}
+}
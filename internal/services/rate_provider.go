@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrRateUnavailable = newError(KindRejected, "rate_unavailable", "exchange rate unavailable")
+
+// RateProvider returns the mid-market rate to convert one unit of from into
+// to.
+type RateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// StaticRateProvider serves rates from a fixed table keyed by "FROM/TO".
+// Missing pairs fall back to the inverse of the opposite pair.
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+func NewStaticRateProvider(table map[string]string) (*StaticRateProvider, error) {
+	rates := make(map[string]*big.Rat, len(table))
+	for pair, value := range table {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || len(from) != 3 || len(to) != 3 {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, pair)
+		}
+		rates[pair] = rate
+	}
+	return &StaticRateProvider{rates: rates}, nil
+}
+
+func (p *StaticRateProvider) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if rate, ok := p.rates[from+"/"+to]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := p.rates[to+"/"+from]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, ErrRateUnavailable.withMessage("exchange rate unavailable: %s/%s", from, to)
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"ledger/internal/repository"
+	"math/rand"
+	"time"
+)
+
+const (
+	maxTransactionAttempts = 5
+	retryBaseDelay         = 10 * time.Millisecond
+)
+
+// retryTransaction runs fn in a database transaction, running it again with
+// a jittered backoff when the store aborts it with a serialization failure
+// or a deadlock. fn must reload whatever it locks, since each attempt starts
+// from scratch.
+func (s *LedgerService) retryTransaction(fn func(tx repository.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.repo.Transaction(fn)
+		if err == nil || attempt == maxTransactionAttempts || !repository.IsRetryable(err) {
+			return err
+		}
+
+		delay := retryBaseDelay << (attempt - 1)
+		time.Sleep(delay + time.Duration(rand.Int63n(int64(delay))))
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+var (
+	ErrAlreadyReversed          = newError(KindConflict, "already_reversed", "transaction is already fully reversed")
+	ErrNotReversible            = newError(KindRejected, "not_reversible", "transaction cannot be reversed")
+	ErrReversalExceedsRemaining = newError(KindRejected, "reversal_exceeds_remaining", "reversal amount exceeds what is left to reverse")
+)
+
+// AlreadyReversedError names the transaction that has nothing left to
+// reverse; it matches ErrAlreadyReversed.
+type AlreadyReversedError struct {
+	TransactionID string
+}
+
+func (e *AlreadyReversedError) Error() string {
+	return fmt.Sprintf("transaction %s is already fully reversed", e.TransactionID)
+}
+
+func (e *AlreadyReversedError) Unwrap() error {
+	return ErrAlreadyReversed
+}
+
+// ReverseTransaction reverses every posting of the journal entry that
+// transactionID belongs to. A zero amount reverses whatever has not been
+// reversed yet; otherwise amount is taken off each posting of the entry.
+func (s *LedgerService) ReverseTransaction(ctx context.Context, transactionID string, amount models.Amount, idem *Idempotency) (*models.JournalEntry, error) {
+	s = s.scoped(ctx)
+	if amount < 0 {
+		return nil, ErrInvalidAmount.withMessage("reversal amount cannot be negative")
+	}
+
+	var reversal *models.JournalEntry
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		posting, err := s.repo.GetTransactionByIDInTx(tx, transactionID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+
+		if posting.JournalEntryID == "" {
+			return ErrNotReversible.withMessage("transaction is not part of a journal entry")
+		}
+
+		original, err := s.repo.GetJournalEntryByIDInTx(tx, posting.JournalEntryID)
+		if err != nil {
+			return err
+		}
+
+		if original.Type == models.JournalEntryTypeReversal {
+			return ErrNotReversible.withMessage("cannot reverse a reversal transaction")
+		}
+
+		ids := make([]string, 0, len(original.Postings))
+		postingIDs := make([]string, 0, len(original.Postings))
+		for _, p := range original.Postings {
+			ids = append(ids, p.AccountID)
+			postingIDs = append(postingIDs, p.ID)
+		}
+
+		accounts, err := s.lockAccounts(tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		reversed, err := s.repo.GetReversedAmountsInTx(tx, postingIDs)
+		if err != nil {
+			return err
+		}
+
+		postings, err := reversalPostings(original, reversed, amount)
+		if err != nil {
+			if errors.Is(err, errNothingToReverse) {
+				return &AlreadyReversedError{TransactionID: transactionID}
+			}
+			return err
+		}
+
+		for _, p := range postings {
+			if p.Type == models.TransactionTypeDebit {
+				if err := checkSettlement(accounts[p.AccountID], p.Amount); err != nil {
+					return err
+				}
+			}
+		}
+
+		reversal = &models.JournalEntry{
+			Type:           models.JournalEntryTypeReversal,
+			Description:    "Reversal of transaction " + transactionID,
+			ReversalOfID:   &original.ID,
+			CreatedByKeyID: auth.KeyID(ctx),
+			Postings:       postings,
+		}
+		if err := s.postJournalEntry(tx, reversal, accounts); err != nil {
+			return err
+		}
+
+		if err := s.publishEvent(tx, models.EventTransactionReversed, reversal.ID, transactionReversedEvent(transactionID, reversal)); err != nil {
+			return err
+		}
+
+		return s.saveIdempotentResponse(tx, idem, reversal)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+var errNothingToReverse = errors.New("nothing left to reverse")
+
+func reversalPostings(original *models.JournalEntry, reversed map[string]models.Amount, amount models.Amount) ([]models.Transaction, error) {
+	if amount > 0 {
+		for _, p := range original.Postings {
+			if p.Amount != original.Postings[0].Amount {
+				return nil, ErrNotReversible.withMessage("partial reversal is not supported for this journal entry")
+			}
+		}
+	}
+
+	postings := make([]models.Transaction, 0, len(original.Postings))
+	for _, p := range original.Postings {
+		remaining := p.Amount - reversed[p.ID]
+		if remaining <= 0 {
+			return nil, errNothingToReverse
+		}
+
+		reverseAmount := remaining
+		if amount > 0 {
+			if amount > remaining {
+				return nil, ErrReversalExceedsRemaining.withMessage("reversal amount exceeds the %s left to reverse", remaining)
+			}
+			reverseAmount = amount
+		}
+
+		reverseType := models.TransactionTypeCredit
+		if p.Type == models.TransactionTypeCredit {
+			reverseType = models.TransactionTypeDebit
+		}
+
+		postingID := p.ID
+		postings = append(postings, models.Transaction{
+			AccountID:    p.AccountID,
+			Type:         reverseType,
+			Amount:       reverseAmount,
+			ReversalOfID: &postingID,
+		})
+	}
+
+	return postings, nil
+}
+
+// attachReversals fills ReversedBy on each posting with the IDs of the
+// postings that reverse it.
+func (s *LedgerService) attachReversals(postings []models.Transaction) error {
+	if len(postings) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(postings))
+	for i, p := range postings {
+		ids[i] = p.ID
+	}
+
+	reversals, err := s.repo.GetReversalsOf(ids)
+	if err != nil {
+		return err
+	}
+
+	reversedBy := make(map[string][]string, len(reversals))
+	for _, r := range reversals {
+		reversedBy[*r.ReversalOfID] = append(reversedBy[*r.ReversalOfID], r.ID)
+	}
+
+	for i := range postings {
+		postings[i].ReversedBy = reversedBy[postings[i].ID]
+	}
+	return nil
+}
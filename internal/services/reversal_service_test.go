@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/repository/memory"
+	"testing"
+)
+
+func TestReverseTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	entry, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "40.00")}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	postingID := entry.Postings[0].ID
+
+	partial, err := s.ReverseTransaction(ctx, postingID, amount(t, "15.00"), nil)
+	if err != nil {
+		t.Fatalf("partial ReverseTransaction: %v", err)
+	}
+	if partial.ReversalOfID == nil || *partial.ReversalOfID != entry.ID {
+		t.Fatalf("reversal points at %v, want %s", partial.ReversalOfID, entry.ID)
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "75.00", to.ID: "25.00"})
+
+	if _, err := s.ReverseTransaction(ctx, postingID, amount(t, "25.01"), nil); !errors.Is(err, ErrReversalExceedsRemaining) {
+		t.Fatalf("reversing more than remains = %v, want %v", err, ErrReversalExceedsRemaining)
+	}
+
+	if _, err := s.ReverseTransaction(ctx, postingID, 0, nil); err != nil {
+		t.Fatalf("ReverseTransaction of the rest: %v", err)
+	}
+	wantBalances(t, s, ctx, map[string]string{from.ID: "100.00", to.ID: "0"})
+
+	_, err = s.ReverseTransaction(ctx, postingID, 0, nil)
+	var already *AlreadyReversedError
+	if !errors.As(err, &already) || !errors.Is(err, ErrAlreadyReversed) {
+		t.Fatalf("reversing twice = %v, want %v", err, ErrAlreadyReversed)
+	}
+
+	stored, err := s.GetJournalEntry(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("GetJournalEntry: %v", err)
+	}
+	if got := len(stored.Postings[0].ReversedBy); got != 2 {
+		t.Errorf("posting is reversed by %d postings, want 2", got)
+	}
+}
+
+func TestReverseTransactionRejects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t, memory.NewStore())
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	entry, err := s.CreateTransaction(ctx, TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "40.00")}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	reversal, err := s.ReverseTransaction(ctx, entry.Postings[0].ID, amount(t, "10.00"), nil)
+	if err != nil {
+		t.Fatalf("ReverseTransaction: %v", err)
+	}
+
+	if _, err := s.ReverseTransaction(ctx, "missing", 0, nil); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("reversing a missing transaction = %v, want %v", err, ErrTransactionNotFound)
+	}
+	if _, err := s.ReverseTransaction(ctx, reversal.Postings[0].ID, 0, nil); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("reversing a reversal = %v, want %v", err, ErrNotReversible)
+	}
+	if _, err := s.ReverseTransaction(ctx, entry.Postings[0].ID, -1, nil); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("reversing a negative amount = %v, want %v", err, ErrInvalidAmount)
+	}
+
+	wantBalances(t, s, ctx, map[string]string{from.ID: "70.00", to.ID: "30.00"})
+}
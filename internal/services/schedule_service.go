@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"time"
+)
+
+// scheduleMaxRunsPerClaim bounds how many missed runs of one schedule the
+// catch-up-all policy executes in a single database transaction.
+const scheduleMaxRunsPerClaim = 100
+
+var (
+	ErrScheduleNotFound  = newError(KindNotFound, "schedule_not_found", "scheduled transfer not found")
+	ErrInvalidSchedule   = newError(KindInvalid, "invalid_schedule", "invalid schedule")
+	ErrScheduleNotActive = newError(KindConflict, "schedule_not_active", "scheduled transfer is not active")
+)
+
+type ScheduleRequest struct {
+	TransferRequest
+	Frequency  models.ScheduleFrequency
+	DayOfMonth int
+	Cron       string
+	StartAt    time.Time
+	EndAt      *time.Time
+	CatchUp    models.CatchUpPolicy
+}
+
+// CreateScheduledTransfer stores a schedule for req. A one-off schedule runs
+// at StartAt; a recurring one starts at StartAt, or now when it is zero.
+func (s *LedgerService) CreateScheduledTransfer(ctx context.Context, req ScheduleRequest, idem *Idempotency) (*models.ScheduledTransfer, error) {
+	s = s.scoped(ctx)
+	if err := validateTransfer(req.TransferRequest); err != nil {
+		return nil, err
+	}
+	if req.QuoteID != "" {
+		return nil, ErrInvalidSchedule.withMessage("scheduled transfers cannot use an FX quote")
+	}
+
+	fromAccount, err := s.repo.GetAccountByID(req.FromAccountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound.withMessage("from account not found")
+		}
+		return nil, err
+	}
+	if _, err := s.repo.GetAccountByID(req.ToAccountID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound.withMessage("to account not found")
+		}
+		return nil, err
+	}
+	if err := models.ValidateCurrencyAmount(fromAccount.Currency, req.Amount); err != nil {
+		return nil, err
+	}
+
+	if req.CatchUp == "" {
+		req.CatchUp = s.config.ScheduleCatchUp
+	}
+	if !req.CatchUp.Valid() {
+		return nil, ErrInvalidSchedule.withMessage("invalid catch-up policy")
+	}
+
+	switch req.Frequency {
+	case models.ScheduleFrequencyOnce:
+		if req.StartAt.IsZero() {
+			return nil, ErrInvalidSchedule.withMessage("a one-off scheduled transfer needs an execution time")
+		}
+	case models.ScheduleFrequencyDaily, models.ScheduleFrequencyWeekly:
+	case models.ScheduleFrequencyMonthly:
+		if req.DayOfMonth < 1 || req.DayOfMonth > 31 {
+			return nil, ErrInvalidSchedule.withMessage("monthly schedules need a day of month between 1 and 31")
+		}
+	case models.ScheduleFrequencyCron:
+		if _, err := parseCron(req.Cron); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidSchedule.withMessage("invalid schedule frequency")
+	}
+
+	if req.StartAt.IsZero() {
+		req.StartAt = s.clock.Now()
+	}
+
+	schedule := &models.ScheduledTransfer{
+		FromAccountID:  req.FromAccountID,
+		ToAccountID:    req.ToAccountID,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		Frequency:      req.Frequency,
+		DayOfMonth:     req.DayOfMonth,
+		CronExpression: req.Cron,
+		StartAt:        req.StartAt.UTC(),
+		CatchUp:        req.CatchUp,
+		Status:         models.ScheduleStatusActive,
+		CreatedByKeyID: auth.KeyID(ctx),
+	}
+	if req.EndAt != nil {
+		endAt := req.EndAt.UTC()
+		if endAt.Before(schedule.StartAt) {
+			return nil, ErrInvalidSchedule.withMessage("schedule cannot end before it starts")
+		}
+		schedule.EndAt = &endAt
+	}
+
+	first, ok := nextOccurrence(schedule, schedule.StartAt.Add(-time.Nanosecond))
+	if !ok {
+		return nil, ErrInvalidSchedule.withMessage("schedule has no occurrences")
+	}
+	schedule.NextRunAt = &first
+
+	err = s.repo.Transaction(func(tx repository.Tx) error {
+		if err := s.repo.CreateScheduledTransferInTx(tx, schedule); err != nil {
+			return err
+		}
+		return s.saveIdempotentResponse(tx, idem, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *LedgerService) GetScheduledTransfer(ctx context.Context, scheduleID string) (*models.ScheduledTransfer, error) {
+	s = s.scoped(ctx)
+	schedule, err := s.repo.GetScheduledTransferByID(scheduleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *LedgerService) ListScheduledTransferRuns(ctx context.Context, scheduleID string, limit, offset int) ([]models.ScheduledTransferRun, error) {
+	s = s.scoped(ctx)
+	if _, err := s.GetScheduledTransfer(ctx, scheduleID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetScheduledTransferRuns(scheduleID, limit, offset)
+}
+
+func (s *LedgerService) CancelScheduledTransfer(ctx context.Context, scheduleID string) (*models.ScheduledTransfer, error) {
+	s = s.scoped(ctx)
+	var schedule *models.ScheduledTransfer
+	err := s.repo.Transaction(func(tx repository.Tx) error {
+		var err error
+		schedule, err = s.repo.GetScheduledTransferByIDForUpdate(tx, scheduleID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrScheduleNotFound
+			}
+			return err
+		}
+
+		if schedule.Status != models.ScheduleStatusActive {
+			return ErrScheduleNotActive
+		}
+
+		schedule.Status = models.ScheduleStatusCancelled
+		schedule.NextRunAt = nil
+		return s.repo.UpdateScheduledTransferInTx(tx, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *LedgerService) runScheduler() {
+	defer s.workerPool.Done()
+
+	ticker := time.NewTicker(s.config.SchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDueSchedules()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueSchedules runs schedules one at a time until none is due.
+func (s *LedgerService) runDueSchedules() {
+	for s.ctx.Err() == nil {
+		claimed, err := s.runNextSchedule()
+		if err != nil {
+			slog.Error("Failed to run scheduled transfer", "error", err)
+			return
+		}
+		if !claimed {
+			return
+		}
+	}
+}
+
+// runNextSchedule claims one due schedule with SKIP LOCKED, so replicas
+// never run the same schedule concurrently, and executes its due runs. The
+// transfers, their run records and the schedule's next run time commit
+// together.
+func (s *LedgerService) runNextSchedule() (bool, error) {
+	now := s.clock.Now().UTC()
+
+	var claimed bool
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		schedule, err := s.repo.ClaimDueScheduledTransferInTx(tx, now)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				claimed = false
+				return nil
+			}
+			return err
+		}
+		claimed = true
+
+		if tx, err = s.repo.ScopeTx(tx, schedule.TenantID); err != nil {
+			return err
+		}
+
+		due, next, more := s.dueRuns(schedule, now)
+		for _, at := range due {
+			if err := s.runScheduledTransfer(tx, schedule, at); err != nil {
+				return err
+			}
+			runAt := at
+			schedule.LastRunAt = &runAt
+		}
+
+		if more {
+			schedule.NextRunAt = &next
+		} else {
+			schedule.NextRunAt = nil
+			schedule.Status = models.ScheduleStatusCompleted
+		}
+		return s.repo.UpdateScheduledTransferInTx(tx, schedule)
+	})
+	return claimed, err
+}
+
+// dueRuns returns the occurrences of schedule to execute now, applying its
+// catch-up policy to those it missed, and the occurrence to wait for next.
+func (s *LedgerService) dueRuns(schedule *models.ScheduledTransfer, now time.Time) ([]time.Time, time.Time, bool) {
+	var due []time.Time
+	skipped := 0
+
+	next, more := *schedule.NextRunAt, true
+	for more && !next.After(now) && len(due) < scheduleMaxRunsPerClaim {
+		if schedule.CatchUp != models.CatchUpAll && len(due) > 0 {
+			skipped += len(due)
+			due = due[:0]
+		}
+		due = append(due, next)
+		next, more = nextOccurrence(schedule, next)
+	}
+
+	if schedule.CatchUp == models.CatchUpSkip && len(due) > 0 && now.Sub(due[0]) > s.config.ScheduleMissedAfter {
+		skipped += len(due)
+		due = nil
+	}
+
+	if skipped > 0 {
+		slog.Warn("Skipped missed scheduled transfer runs", "scheduled_transfer_id", schedule.ID, "count", skipped, "catch_up", schedule.CatchUp)
+	}
+	return due, next, more
+}
+
+func (s *LedgerService) runScheduledTransfer(tx repository.Tx, schedule *models.ScheduledTransfer, at time.Time) error {
+	description := schedule.Description
+	if description == "" {
+		description = "Scheduled transfer " + schedule.ID
+	}
+
+	transfer := newTransfer(TransferRequest{
+		FromAccountID:  schedule.FromAccountID,
+		ToAccountID:    schedule.ToAccountID,
+		Amount:         schedule.Amount,
+		Description:    description,
+		CreatedByKeyID: schedule.CreatedByKeyID,
+	}, models.TransferStatusPending)
+	if err := s.settleTransfer(tx, transfer); err != nil {
+		return err
+	}
+	if err := s.repo.CreateTransferInTx(tx, transfer); err != nil {
+		return err
+	}
+
+	return s.repo.CreateScheduledTransferRunInTx(tx, &models.ScheduledTransferRun{
+		TenantID:            schedule.TenantID,
+		ScheduledTransferID: schedule.ID,
+		ScheduledFor:        at,
+		TransferID:          transfer.ID,
+	})
+}
+
+// nextOccurrence returns the first occurrence of schedule strictly after
+// after, or false once the schedule has no more occurrences.
+func nextOccurrence(schedule *models.ScheduledTransfer, after time.Time) (time.Time, bool) {
+	start := schedule.StartAt.UTC()
+	after = after.UTC()
+
+	var next time.Time
+	switch schedule.Frequency {
+	case models.ScheduleFrequencyOnce:
+		if !start.After(after) {
+			return time.Time{}, false
+		}
+		next = start
+	case models.ScheduleFrequencyDaily, models.ScheduleFrequencyWeekly:
+		days := 1
+		if schedule.Frequency == models.ScheduleFrequencyWeekly {
+			days = 7
+		}
+		next = start
+		if !start.After(after) {
+			periods := int(after.Sub(start)/(time.Duration(days)*24*time.Hour)) + 1
+			next = start.AddDate(0, 0, periods*days)
+		}
+	case models.ScheduleFrequencyMonthly:
+		from := after
+		if from.Before(start) {
+			from = start
+		}
+		for i := 0; i < 2; i++ {
+			candidate := monthlyOccurrence(from.Year(), from.Month()+time.Month(i), schedule.DayOfMonth, start)
+			if candidate.After(after) && !candidate.Before(start) {
+				next = candidate
+				break
+			}
+		}
+	case models.ScheduleFrequencyCron:
+		cron, err := parseCron(schedule.CronExpression)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if after.Before(start) {
+			after = start.Add(-time.Nanosecond)
+		}
+		var ok bool
+		if next, ok = cron.next(after); !ok {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if next.IsZero() || (schedule.EndAt != nil && next.After(*schedule.EndAt)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// monthlyOccurrence is day of the given month at start's time of day, moved
+// to the month's last day in months that are too short.
+func monthlyOccurrence(year int, month time.Month, day int, start time.Time) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"ledger/internal/models"
+	"ledger/internal/repository/memory"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a Clock the test moves by hand.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// scheduleAt is the given day of 2025 at 09:00 UTC.
+func scheduleAt(month time.Month, day int) time.Time {
+	return time.Date(2025, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+// newScheduleTest returns a service on clock, set to start, and a schedule
+// of 1.00 between two of its accounts as req describes.
+func newScheduleTest(t *testing.T, start time.Time, req ScheduleRequest) (*LedgerService, *testClock, *models.ScheduledTransfer) {
+	t.Helper()
+	ctx := context.Background()
+	clock := &testClock{now: start.Add(-time.Hour)}
+	s := newTestService(t, memory.NewStore(), WithClock(clock))
+	from := openAccount(t, s, ctx, "100.00", AccountPolicy{})
+	to := openAccount(t, s, ctx, "0", AccountPolicy{})
+
+	req.TransferRequest = TransferRequest{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount(t, "1.00")}
+	req.StartAt = start
+	schedule, err := s.CreateScheduledTransfer(ctx, req, nil)
+	if err != nil {
+		t.Fatalf("CreateScheduledTransfer: %v", err)
+	}
+	return s, clock, schedule
+}
+
+// scheduledRuns returns the times schedule ran for, oldest first.
+func scheduledRuns(t *testing.T, s *LedgerService, schedule *models.ScheduledTransfer) []time.Time {
+	t.Helper()
+	runs, err := s.ListScheduledTransferRuns(context.Background(), schedule.ID, 100, 0)
+	if err != nil {
+		t.Fatalf("ListScheduledTransferRuns: %v", err)
+	}
+	times := make([]time.Time, len(runs))
+	for i, run := range runs {
+		if run.Transfer == nil || run.Transfer.Status != models.TransferStatusCompleted {
+			t.Errorf("run for %s has not completed its transfer", run.ScheduledFor)
+		}
+		times[len(runs)-1-i] = run.ScheduledFor.UTC()
+	}
+	return times
+}
+
+func wantRuns(t *testing.T, got []time.Time, want ...time.Time) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("ran for %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("ran for %v, want %v", got, want)
+		}
+	}
+}
+
+// Each frequency runs at its occurrences and not a second before, as the
+// clock reaches them.
+func TestScheduleOccurrences(t *testing.T) {
+	start := scheduleAt(time.January, 31)
+	tests := []struct {
+		name string
+		req  ScheduleRequest
+		want []time.Time
+	}{
+		{
+			name: "once",
+			req:  ScheduleRequest{Frequency: models.ScheduleFrequencyOnce},
+			want: []time.Time{start},
+		},
+		{
+			name: "daily",
+			req:  ScheduleRequest{Frequency: models.ScheduleFrequencyDaily},
+			want: []time.Time{start, scheduleAt(time.February, 1), scheduleAt(time.February, 2)},
+		},
+		{
+			name: "monthly on day 31",
+			req:  ScheduleRequest{Frequency: models.ScheduleFrequencyMonthly, DayOfMonth: 31},
+			want: []time.Time{start, scheduleAt(time.February, 28), scheduleAt(time.March, 31), scheduleAt(time.April, 30)},
+		},
+		{
+			// Weekdays at 09:30: Friday the 31st, then Monday and Tuesday.
+			name: "cron",
+			req:  ScheduleRequest{Frequency: models.ScheduleFrequencyCron, Cron: "30 9 * * 1-5"},
+			want: []time.Time{
+				start.Add(30 * time.Minute),
+				scheduleAt(time.February, 3).Add(30 * time.Minute),
+				scheduleAt(time.February, 4).Add(30 * time.Minute),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clock, schedule := newScheduleTest(t, start, tt.req)
+
+			for i, at := range tt.want {
+				clock.Set(at.Add(-time.Second))
+				s.runDueSchedules()
+				wantRuns(t, scheduledRuns(t, s, schedule), tt.want[:i]...)
+
+				clock.Set(at)
+				s.runDueSchedules()
+				wantRuns(t, scheduledRuns(t, s, schedule), tt.want[:i+1]...)
+			}
+
+			if tt.req.Frequency == models.ScheduleFrequencyOnce {
+				clock.Set(start.AddDate(1, 0, 0))
+				s.runDueSchedules()
+				wantRuns(t, scheduledRuns(t, s, schedule), tt.want...)
+
+				stored, err := s.GetScheduledTransfer(context.Background(), schedule.ID)
+				if err != nil {
+					t.Fatalf("GetScheduledTransfer: %v", err)
+				}
+				if stored.Status != models.ScheduleStatusCompleted || stored.NextRunAt != nil {
+					t.Errorf("schedule is %s, next run %v, want it completed", stored.Status, stored.NextRunAt)
+				}
+			}
+		})
+	}
+}
+
+// A daily schedule that comes back to four missed runs handles them as its
+// catch-up policy says, and carries on with the next day either way.
+func TestScheduleCatchUp(t *testing.T) {
+	start := scheduleAt(time.January, 31)
+	missed := []time.Time{start, scheduleAt(time.February, 1), scheduleAt(time.February, 2), scheduleAt(time.February, 3)}
+	tests := []struct {
+		name    string
+		policy  models.CatchUpPolicy
+		resumed time.Time
+		want    []time.Time
+	}{
+		{"all", models.CatchUpAll, missed[3].Add(time.Hour), missed},
+		{"latest", models.CatchUpLatest, missed[3].Add(time.Hour), missed[3:]},
+		{"skip", models.CatchUpSkip, missed[3].Add(time.Hour), nil},
+		// Within ScheduleMissedAfter, a run is late rather than missed.
+		{"skip when barely late", models.CatchUpSkip, missed[3].Add(2 * time.Minute), missed[3:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, clock, schedule := newScheduleTest(t, start, ScheduleRequest{Frequency: models.ScheduleFrequencyDaily, CatchUp: tt.policy})
+
+			clock.Set(tt.resumed)
+			s.runDueSchedules()
+			wantRuns(t, scheduledRuns(t, s, schedule), tt.want...)
+			if got, want := balanceOf(t, s, context.Background(), schedule.ToAccountID), models.Amount(len(tt.want))*amount(t, "1.00"); got != want {
+				t.Errorf("transferred %s, want %s", got, want)
+			}
+
+			next := scheduleAt(time.February, 4)
+			clock.Set(next)
+			s.runDueSchedules()
+			wantRuns(t, scheduledRuns(t, s, schedule), append(append([]time.Time(nil), tt.want...), next)...)
+		})
+	}
+}
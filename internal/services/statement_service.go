@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"time"
+)
+
+const maxStatementLines = 10000
+
+type Statement struct {
+	AccountID      string
+	OwnerName      string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance models.Amount
+	ClosingBalance models.Amount
+	TotalDebits    models.Amount
+	TotalCredits   models.Amount
+	Lines          []StatementLine
+}
+
+type StatementLine struct {
+	TransactionID  string
+	JournalEntryID string
+	Date           time.Time
+	Description    string
+	Type           models.TransactionType
+	Amount         models.Amount
+	RunningBalance models.Amount
+}
+
+// GetStatement lists the account's postings on the days from from to to,
+// both inclusive, with the balance after each of them.
+func (s *LedgerService) GetStatement(ctx context.Context, accountID string, from, to time.Time) (*Statement, error) {
+	s = s.scoped(ctx)
+	account, err := s.repo.GetAccountByID(accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	from, to = models.StartOfDay(from), models.StartOfDay(to)
+	if to.Before(from) {
+		return nil, ErrInvalidRange
+	}
+
+	opening, err := s.openingBalance(accountID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	postings, err := s.repo.GetPostingsBetween(accountID, from, to.AddDate(0, 0, 1), maxStatementLines+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(postings) > maxStatementLines {
+		return nil, ErrInvalidRange.withMessage("statement has more than %d lines, use a shorter period", maxStatementLines)
+	}
+
+	statement := &Statement{
+		AccountID:      account.ID,
+		OwnerName:      account.OwnerName,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		Lines:          make([]StatementLine, len(postings)),
+	}
+
+	balance := opening
+	for i, p := range postings {
+		balance += p.SignedAmount()
+		if p.Type == models.TransactionTypeDebit {
+			statement.TotalDebits += p.Amount
+		} else {
+			statement.TotalCredits += p.Amount
+		}
+
+		statement.Lines[i] = StatementLine{
+			TransactionID:  p.ID,
+			JournalEntryID: p.JournalEntryID,
+			Date:           p.CreatedAt,
+			Description:    p.Description,
+			Type:           p.Type,
+			Amount:         p.Amount,
+			RunningBalance: balance,
+		}
+	}
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"ledger/internal/repository/memory"
+	"ledger/internal/repository/storetest"
+	"ledger/internal/tenant"
+	"testing"
+	"time"
+)
+
+// One tenant can neither see nor move another tenant's funds, whatever it
+// names them by, on every store. The Postgres run also goes through its
+// row-level security policies.
+func TestTenantIsolation(t *testing.T) {
+	stores := []struct {
+		name string
+		open func(t *testing.T) repository.LedgerStore
+	}{
+		{"Memory", func(*testing.T) repository.LedgerStore { return memory.NewStore() }},
+		{"SQLite", storetest.NewSQLiteStore},
+		{"Postgres", storetest.NewPostgresStore},
+	}
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			testTenantIsolation(t, newTestService(t, store.open(t)))
+		})
+	}
+}
+
+func testTenantIsolation(t *testing.T, s *LedgerService) {
+	ctxA := tenant.WithID(context.Background(), models.NewID())
+	ctxB := tenant.WithID(context.Background(), models.NewID())
+
+	a := openAccount(t, s, ctxA, "100.00", AccountPolicy{})
+	a2 := openAccount(t, s, ctxA, "0", AccountPolicy{})
+	b := openAccount(t, s, ctxB, "100.00", AccountPolicy{})
+
+	entry, err := s.CreateTransaction(ctxA, TransferRequest{FromAccountID: a.ID, ToAccountID: a2.ID, Amount: amount(t, "10.00")}, nil)
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	hold, err := s.CreateHold(ctxA, a.ID, amount(t, "5.00"), "", time.Time{})
+	if err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+	overdraft := amount(t, "20.00")
+	if _, err := s.UpdateAccountPolicy(ctxA, a2.ID, AccountPolicyUpdate{OverdraftLimit: &overdraft}, "test"); err != nil {
+		t.Fatalf("UpdateAccountPolicy: %v", err)
+	}
+	if _, err := s.ChangeAccountStatus(ctxA, a2.ID, models.AccountStatusFrozen, "test"); err != nil {
+		t.Fatalf("ChangeAccountStatus: %v", err)
+	}
+
+	wantNotFound := func(t *testing.T, what string, err error) {
+		t.Helper()
+		var ledgerErr *LedgerError
+		if !errors.As(err, &ledgerErr) || ledgerErr.Kind != KindNotFound {
+			t.Errorf("%s from another tenant = %v, want not found", what, err)
+		}
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		_, err := s.GetAccount(ctxB, a.ID)
+		wantNotFound(t, "GetAccount", err)
+		_, err = s.GetBalance(ctxB, a.ID)
+		wantNotFound(t, "GetBalance", err)
+		_, err = s.GetJournalEntry(ctxB, entry.ID)
+		wantNotFound(t, "GetJournalEntry", err)
+		_, err = s.GetHold(ctxB, hold.ID)
+		wantNotFound(t, "GetHold", err)
+		_, err = s.GetAccountAuditLog(ctxB, a2.ID)
+		wantNotFound(t, "GetAccountAuditLog", err)
+		_, err = s.GetAccountStatusHistory(ctxB, a2.ID)
+		wantNotFound(t, "GetAccountStatusHistory", err)
+
+		accounts, err := s.ListAccounts(ctxB, 100, 0)
+		if err != nil {
+			t.Fatalf("ListAccounts: %v", err)
+		}
+		if len(accounts) != 1 || accounts[0].ID != b.ID {
+			t.Errorf("ListAccounts lists %d accounts, want only the tenant's own", len(accounts))
+		}
+		page, err := s.ListTransactions(ctxB, TransactionFilter{AccountID: a.ID}, "", 100)
+		if err != nil {
+			t.Fatalf("ListTransactions: %v", err)
+		}
+		if len(page.Transactions) != 0 {
+			t.Errorf("ListTransactions lists %d of another tenant's postings, want none", len(page.Transactions))
+		}
+	})
+
+	// The records kept beside the accounts are the owner's too, even to a
+	// lookup that goes straight to them.
+	t.Run("history", func(t *testing.T) {
+		repo := s.scoped(ctxB).repo
+		logs, err := repo.GetAuditLogs("account", a2.ID)
+		if err != nil {
+			t.Fatalf("GetAuditLogs: %v", err)
+		}
+		changes, err := repo.GetAccountStatusChanges(a2.ID)
+		if err != nil {
+			t.Fatalf("GetAccountStatusChanges: %v", err)
+		}
+		snapshot, err := repo.GetLastBalanceSnapshotBefore(a.ID, time.Now().Add(48*time.Hour))
+		if err != nil {
+			t.Fatalf("GetLastBalanceSnapshotBefore: %v", err)
+		}
+		if len(logs) != 0 || len(changes) != 0 || snapshot != nil {
+			t.Errorf("another tenant sees %d audit logs, %d status changes and snapshot %v, want none", len(logs), len(changes), snapshot)
+		}
+	})
+
+	t.Run("moves", func(t *testing.T) {
+		_, err := s.CreateTransaction(ctxB, TransferRequest{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "1.00")}, nil)
+		wantNotFound(t, "CreateTransaction from its account", err)
+		_, err = s.CreateTransaction(ctxB, TransferRequest{FromAccountID: b.ID, ToAccountID: a.ID, Amount: amount(t, "1.00")}, nil)
+		wantNotFound(t, "CreateTransaction to its account", err)
+		transfer, err := s.SubmitTransfer(ctxB, TransferRequest{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "1.00")}, false, nil)
+		if err != nil {
+			t.Fatalf("SubmitTransfer: %v", err)
+		}
+		if transfer.Status != models.TransferStatusFailed || transfer.FailureCode != ErrAccountNotFound.Code {
+			t.Errorf("transfer is %s (%s), want it failed with %s", transfer.Status, transfer.FailureCode, ErrAccountNotFound.Code)
+		}
+		batch, err := s.CreateTransferBatch(ctxB, []TransferRequest{{FromAccountID: a.ID, ToAccountID: b.ID, Amount: amount(t, "1.00")}}, nil)
+		if err != nil {
+			t.Fatalf("CreateTransferBatch: %v", err)
+		}
+		if batch.Status != models.TransferBatchStatusFailed {
+			t.Errorf("batch is %s, want it failed", batch.Status)
+		}
+		_, err = s.ReverseTransaction(ctxB, entry.Postings[0].ID, 0, nil)
+		wantNotFound(t, "ReverseTransaction", err)
+		_, err = s.CreateHold(ctxB, a.ID, amount(t, "1.00"), "", time.Time{})
+		wantNotFound(t, "CreateHold", err)
+		_, err = s.CaptureHold(ctxB, hold.ID, b.ID, 0, "")
+		wantNotFound(t, "CaptureHold", err)
+		_, err = s.VoidHold(ctxB, hold.ID)
+		wantNotFound(t, "VoidHold", err)
+		_, err = s.ChangeAccountStatus(ctxB, a.ID, models.AccountStatusClosed, "test")
+		wantNotFound(t, "ChangeAccountStatus", err)
+		_, err = s.UpdateAccountPolicy(ctxB, a.ID, AccountPolicyUpdate{OverdraftLimit: &overdraft}, "test")
+		wantNotFound(t, "UpdateAccountPolicy", err)
+	})
+
+	wantBalances(t, s, ctxA, map[string]string{a.ID: "90.00", a2.ID: "10.00"})
+	wantBalances(t, s, ctxB, map[string]string{b.ID: "100.00"})
+	stored, err := s.GetHold(ctxA, hold.ID)
+	if err != nil {
+		t.Fatalf("GetHold: %v", err)
+	}
+	if stored.Status != models.HoldStatusActive {
+		t.Errorf("hold is %s, want it still active", stored.Status)
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"ledger/internal/auth"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"time"
+)
+
+const transferRecoveryBatchSize = 100
+
+var (
+	ErrTransferNotFound  = newError(KindNotFound, "transfer_not_found", "transfer not found")
+	ErrTransferQueueFull = newError(KindUnavailable, "queue_full", "transfer queue is full, try again later")
+)
+
+// SubmitTransfer persists req as a pending transfer and queues it for the
+// workers. In async mode it returns straight away; otherwise it waits up to
+// the configured transfer timeout for the outcome and returns the transfer
+// as it stands then, which may still be pending.
+func (s *LedgerService) SubmitTransfer(ctx context.Context, req TransferRequest, async bool, idem *Idempotency) (*models.Transfer, error) {
+	s = s.scoped(ctx)
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	req.CreatedByKeyID = auth.KeyID(ctx)
+
+	transfer := newTransfer(req, models.TransferStatusPending)
+	err := s.repo.Transaction(func(tx repository.Tx) error {
+		if err := s.repo.CreateTransferInTx(tx, transfer); err != nil {
+			return err
+		}
+		return s.saveIdempotentResponse(tx, idem, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if async {
+		s.enqueueTransfer(transfer.ID, nil, nil)
+		return transfer, nil
+	}
+
+	resultChan := make(chan TransactionResult, 1)
+	if !s.enqueueTransfer(transfer.ID, idem, resultChan) {
+		return transfer, nil
+	}
+
+	timer := time.NewTimer(s.config.TransferTimeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-resultChan:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Transfer, nil
+	case <-timer.C:
+		return transfer, nil
+	case <-ctx.Done():
+		return transfer, nil
+	case <-s.ctx.Done():
+		return transfer, nil
+	}
+}
+
+func (s *LedgerService) GetTransfer(ctx context.Context, transferID string) (*models.Transfer, error) {
+	s = s.scoped(ctx)
+	transfer, err := s.repo.GetTransferByID(transferID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func newTransfer(req TransferRequest, status models.TransferStatus) *models.Transfer {
+	transfer := &models.Transfer{
+		FromAccountID:  req.FromAccountID,
+		ToAccountID:    req.ToAccountID,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		Status:         status,
+		CreatedByKeyID: req.CreatedByKeyID,
+	}
+	if req.QuoteID != "" {
+		transfer.QuoteID = &req.QuoteID
+	}
+	return transfer
+}
+
+// enqueueTransfer hands a pending transfer to the workers without blocking.
+// When the queue is full or the transfer is already queued it is left to
+// recoverTransfers to pick up later.
+func (s *LedgerService) enqueueTransfer(transferID string, idem *Idempotency, resultChan chan TransactionResult) bool {
+	if _, queued := s.queuedTransfers.LoadOrStore(transferID, struct{}{}); queued {
+		return false
+	}
+
+	select {
+	case s.jobQueue <- &TransactionJob{TransferID: transferID, Idempotency: idem, ResultChan: resultChan}:
+		return true
+	default:
+		s.queuedTransfers.Delete(transferID)
+		return false
+	}
+}
+
+// processTransfer executes a pending transfer, scoped to its tenant, and
+// records its outcome. The transfer row is locked so that only one worker,
+// on any instance, settles it. A transfer that cannot be executed is marked
+// failed with the reason; if recording the outcome fails it stays pending
+// for a later attempt.
+func (s *LedgerService) processTransfer(transferID string, idem *Idempotency) (*models.Transfer, error) {
+	defer s.queuedTransfers.Delete(transferID)
+
+	var transfer *models.Transfer
+	err := s.retryTransaction(func(tx repository.Tx) error {
+		var err error
+		transfer, err = s.repo.GetTransferByIDForUpdate(tx, transferID)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != models.TransferStatusPending {
+			return nil
+		}
+
+		if tx, err = s.repo.ScopeTx(tx, transfer.TenantID); err != nil {
+			return err
+		}
+
+		if err := s.settleTransfer(tx, transfer); err != nil {
+			return err
+		}
+
+		if err := s.repo.UpdateTransferInTx(tx, transfer); err != nil {
+			return err
+		}
+		return s.updateIdempotentResponse(tx, idem, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// settleTransfer executes transfer inside a savepoint of tx and sets its
+// status to completed or, with the reason, failed. It only returns an error
+// when tx itself has to be retried.
+func (s *LedgerService) settleTransfer(tx repository.Tx, transfer *models.Transfer) error {
+	req := TransferRequest{
+		FromAccountID:  transfer.FromAccountID,
+		ToAccountID:    transfer.ToAccountID,
+		Amount:         transfer.Amount,
+		Description:    transfer.Description,
+		CreatedByKeyID: transfer.CreatedByKeyID,
+	}
+	if transfer.QuoteID != nil {
+		req.QuoteID = *transfer.QuoteID
+	}
+
+	var entry *models.JournalEntry
+	execErr := s.repo.Savepoint(tx, func(tx repository.Tx) error {
+		var err error
+		entry, err = s.executeTransfer(tx, req)
+		return err
+	})
+	if repository.IsRetryable(execErr) {
+		return execErr
+	}
+
+	now := s.clock.Now()
+	transfer.CompletedAt = &now
+	if execErr != nil {
+		transfer.Status = models.TransferStatusFailed
+		transfer.FailureReason = execErr.Error()
+		var ledgerErr *LedgerError
+		if errors.As(execErr, &ledgerErr) {
+			transfer.FailureCode = ledgerErr.Code
+		}
+	} else {
+		transfer.Status = models.TransferStatusCompleted
+		transfer.JournalEntryID = &entry.ID
+	}
+	return nil
+}
+
+// updateIdempotentResponse replaces the response stored when a synchronous
+// transfer was submitted with the transfer's final outcome.
+func (s *LedgerService) updateIdempotentResponse(tx repository.Tx, idem *Idempotency, result interface{}) error {
+	if idem == nil {
+		return nil
+	}
+
+	status, body := idem.Response(result)
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateIdempotencyResponseInTx(tx, idem.Key, status, payload)
+}
+
+// recoverTransfers queues pending transfers that are not in the in-memory
+// queue, such as those submitted before a restart or while the queue was
+// full.
+func (s *LedgerService) recoverTransfers() {
+	defer s.workerPool.Done()
+
+	ticker := time.NewTicker(s.config.TransferPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := s.repo.GetPendingTransferIDs(transferRecoveryBatchSize)
+		if err != nil {
+			slog.Error("Failed to load pending transfers", "error", err)
+		}
+		for _, id := range ids {
+			s.enqueueTransfer(id, nil, nil)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
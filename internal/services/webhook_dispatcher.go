@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	outboxFanOutBatchSize  = 100
+	webhookDeliveryBatch   = 50
+	webhookLastErrorLength = 500
+
+	WebhookSignatureHeader = "Ledger-Signature"
+	WebhookEventIDHeader   = "Ledger-Event-Id"
+	WebhookEventTypeHeader = "Ledger-Event-Type"
+)
+
+// dispatchWebhooks fans new outbox events out to the subscribed webhooks
+// and sends the deliveries that are due.
+func (s *LedgerService) dispatchWebhooks() {
+	defer s.workerPool.Done()
+
+	client := &http.Client{Timeout: s.config.WebhookTimeout}
+	ticker := time.NewTicker(s.config.WebhookDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := s.fanOutEvents()
+				if err != nil {
+					slog.Error("Failed to fan out outbox events", "error", err)
+					break
+				}
+				if n < outboxFanOutBatchSize {
+					break
+				}
+			}
+
+			s.sendDueDeliveries(client)
+		}
+	}
+}
+
+// fanOutEvents creates a pending delivery of each undispatched event for
+// every active webhook of the event's tenant subscribed to its type.
+func (s *LedgerService) fanOutEvents() (int, error) {
+	var claimed int
+	err := s.repo.Transaction(func(tx repository.Tx) error {
+		events, err := s.repo.ClaimUndispatchedEventsInTx(tx, outboxFanOutBatchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		claimed = len(events)
+
+		endpoints, err := s.repo.GetActiveWebhookEndpointsInTx(tx)
+		if err != nil {
+			return err
+		}
+
+		now := s.clock.Now()
+		var deliveries []models.WebhookDelivery
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+			for _, endpoint := range endpoints {
+				if endpoint.TenantID == event.TenantID && endpoint.Subscribes(event.Type) {
+					deliveries = append(deliveries, models.WebhookDelivery{
+						TenantID:      event.TenantID,
+						EventID:       event.ID,
+						EndpointID:    endpoint.ID,
+						Status:        models.WebhookDeliveryPending,
+						NextAttemptAt: now,
+					})
+				}
+			}
+		}
+
+		if err := s.repo.CreateWebhookDeliveriesInTx(tx, deliveries); err != nil {
+			return err
+		}
+		return s.repo.MarkEventsDispatchedInTx(tx, ids, now)
+	})
+	return claimed, err
+}
+
+func (s *LedgerService) sendDueDeliveries(client *http.Client) {
+	// A batch is sent one delivery at a time, each taking up to the
+	// timeout, so the lease covers every send of the batch and one more for
+	// recording the attempts. The last delivery is still leased when it is
+	// sent, and a crash mid-batch only delays the rest.
+	lease := (webhookDeliveryBatch + 1) * s.config.WebhookTimeout
+	for s.ctx.Err() == nil {
+		deliveries, err := s.repo.ClaimDueWebhookDeliveries(s.clock.Now(), lease, webhookDeliveryBatch)
+		if err != nil {
+			slog.Error("Failed to claim webhook deliveries", "error", err)
+			return
+		}
+
+		for i := range deliveries {
+			s.attemptDelivery(client, &deliveries[i])
+		}
+
+		if len(deliveries) < webhookDeliveryBatch {
+			return
+		}
+	}
+}
+
+func (s *LedgerService) attemptDelivery(client *http.Client, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+
+	statusCode, err := s.sendWebhook(client, delivery)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := s.clock.Now()
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = truncate(err.Error(), webhookLastErrorLength)
+		if delivery.Attempts >= s.config.WebhookMaxAttempts {
+			delivery.Status = models.WebhookDeliveryDead
+			slog.Warn("Webhook delivery dead-lettered", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", err)
+		} else {
+			delivery.NextAttemptAt = s.clock.Now().Add(s.webhookBackoff(delivery.Attempts))
+		}
+	}
+
+	if err := s.repo.UpdateWebhookDeliveryAttempt(delivery); err != nil {
+		slog.Error("Failed to record webhook delivery attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// sendWebhook posts the delivery's event to its endpoint. Any response
+// outside 2xx counts as a failure.
+func (s *LedgerService) sendWebhook(client *http.Client, delivery *models.WebhookDelivery) (int, error) {
+	if delivery.Event == nil || delivery.Endpoint == nil {
+		return 0, fmt.Errorf("delivery %s has no event or endpoint", delivery.ID)
+	}
+	if !delivery.Endpoint.Active {
+		return 0, errors.New("webhook is disabled")
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventIDHeader, delivery.Event.ID)
+	req.Header.Set(WebhookEventTypeHeader, delivery.Event.Type)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(delivery.Endpoint.Secret, s.clock.Now(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the signature header value for body: the send time
+// and the hex HMAC-SHA256 of "<unix time>.<body>" under the endpoint's
+// secret. Receivers recompute it and should reject stale timestamps.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the wait after every failed attempt, capped at the
+// configured maximum.
+func (s *LedgerService) webhookBackoff(attempts int) time.Duration {
+	delay := s.config.WebhookBackoffBase
+	for i := 1; i < attempts && delay < s.config.WebhookBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.config.WebhookBackoffMax {
+		delay = s.config.WebhookBackoffMax
+	}
+	return delay
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"ledger/internal/models"
+	"ledger/internal/repository"
+)
+
+var (
+	ErrWebhookNotFound         = newError(KindNotFound, "webhook_not_found", "webhook not found")
+	ErrWebhookDeliveryNotFound = newError(KindNotFound, "webhook_delivery_not_found", "webhook delivery not found")
+	ErrUnknownEventType        = newError(KindInvalid, "unknown_event_type", "unknown event type")
+)
+
+var eventTypes = map[string]bool{
+	models.EventTransferCompleted:   true,
+	models.EventAccountCreated:      true,
+	models.EventTransactionReversed: true,
+}
+
+type WebhookUpdate struct {
+	URL         *string
+	EventTypes  *[]string
+	Description *string
+	Active      *bool
+}
+
+// CreateWebhook registers url for eventTypes, or for every event when
+// eventTypes is empty. The returned endpoint carries the signing secret,
+// which is not shown again.
+func (s *LedgerService) CreateWebhook(ctx context.Context, url string, eventTypes []string, description string) (*models.WebhookEndpoint, error) {
+	s = s.scoped(ctx)
+	if err := validateEventTypes(eventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		URL:         url,
+		Secret:      secret,
+		EventTypes:  models.StringList(eventTypes),
+		Description: description,
+		Active:      true,
+	}
+	if err := s.repo.CreateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (s *LedgerService) ListWebhooks(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	s = s.scoped(ctx)
+	return s.repo.GetWebhookEndpoints()
+}
+
+func (s *LedgerService) GetWebhook(ctx context.Context, webhookID string) (*models.WebhookEndpoint, error) {
+	s = s.scoped(ctx)
+	endpoint, err := s.repo.GetWebhookEndpointByID(webhookID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *LedgerService) UpdateWebhook(ctx context.Context, webhookID string, update WebhookUpdate) (*models.WebhookEndpoint, error) {
+	s = s.scoped(ctx)
+	endpoint, err := s.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		endpoint.URL = *update.URL
+	}
+	if update.EventTypes != nil {
+		if err := validateEventTypes(*update.EventTypes); err != nil {
+			return nil, err
+		}
+		endpoint.EventTypes = models.StringList(*update.EventTypes)
+	}
+	if update.Description != nil {
+		endpoint.Description = *update.Description
+	}
+	if update.Active != nil {
+		endpoint.Active = *update.Active
+	}
+
+	if err := s.repo.UpdateWebhookEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *LedgerService) DeleteWebhook(ctx context.Context, webhookID string) error {
+	s = s.scoped(ctx)
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return err
+	}
+	return s.repo.DeleteWebhookEndpoint(webhookID)
+}
+
+func (s *LedgerService) ListWebhookDeliveries(ctx context.Context, webhookID string, status models.WebhookDeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error) {
+	s = s.scoped(ctx)
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWebhookDeliveries(webhookID, status, limit, offset)
+}
+
+// RedeliverWebhook queues a delivery to be sent again straight away with a
+// fresh set of attempts, whatever its current state.
+func (s *LedgerService) RedeliverWebhook(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	s = s.scoped(ctx)
+	delivery, err := s.repo.GetWebhookDeliveryByID(deliveryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	// Deliveries have no tenant of their own; they belong to their webhook's.
+	if _, err := s.GetWebhook(ctx, delivery.EndpointID); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = s.clock.Now()
+	if err := s.repo.UpdateWebhookDeliveryAttempt(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func validateEventTypes(types []string) error {
+	for _, t := range types {
+		if !eventTypes[t] {
+			return ErrUnknownEventType
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,42 @@
+// Package tenant carries the tenant a unit of work belongs to. The
+// repository scopes every query to the tenant found on its context. Work
+// that spans tenants, such as the background workers', says so with
+// WithBypass; on Postgres, work with neither sees no tenant's rows.
+package tenant
+
+import "context"
+
+// Default owns the rows written before the ledger was multi-tenant.
+const Default = "default"
+
+type tenantKey struct{}
+
+type bypassKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithBypass marks ctx as work across every tenant. A tenant on the context
+// takes precedence, so work that learns its tenant is scoped to it again.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether ctx is work across every tenant: it was marked
+// with WithBypass and carries no tenant.
+func Bypassed(ctx context.Context) bool {
+	if _, ok := FromContext(ctx); ok || ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
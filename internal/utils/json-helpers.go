@@ -10,7 +10,7 @@ import (
 
 func DecodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validator.Validate, data interface{}) bool {
 	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
-		ErrorResponse(w, r, http.StatusBadRequest, "error decoding json: "+err.Error())
+		ProblemResponse(w, r, http.StatusBadRequest, "invalid_json", "error decoding json: "+err.Error())
 		return true
 	}
 	if err := validate.Struct(data); err != nil {
@@ -21,16 +21,7 @@ func DecodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validat
 	return false
 }
 
-func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, message string) {
-	render.Status(r, status)
-	render.JSON(w, r, map[string]string{
-		"error": message,
-	})
-}
-
 func ValidationErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	render.Status(r, http.StatusBadRequest)
-
 	validationErrors := make(map[string]string)
 	if errs, ok := err.(validator.ValidationErrors); ok {
 		for _, e := range errs {
@@ -46,17 +37,29 @@ func ValidationErrorResponse(w http.ResponseWriter, r *http.Request, err error)
 				validationErrors[field] = "Must be greater than or equal to " + e.Param()
 			case "gt":
 				validationErrors[field] = "Must be greater than " + e.Param()
+			case "url":
+				validationErrors[field] = "Invalid URL"
 			case "email":
 				validationErrors[field] = "Invalid email"
+			case "oneof":
+				validationErrors[field] = "Must be one of: " + e.Param()
+			case "iso4217":
+				validationErrors[field] = "Invalid ISO 4217 currency code"
+			case "required_without":
+				validationErrors[field] = "This field is required when " + e.Param() + " is not set"
+			case "excluded_with":
+				validationErrors[field] = "Cannot be combined with " + e.Param()
 			default:
 				validationErrors[field] = "Validation failed: " + e.Tag()
 			}
 		}
 	}
 
-	render.JSON(w, r, map[string]interface{}{
-		"error":  "Validation failed",
-		"fields": validationErrors,
+	WriteProblem(w, r, &Problem{
+		Status: http.StatusBadRequest,
+		Code:   "validation_failed",
+		Detail: "Validation failed",
+		Fields: validationErrors,
 	})
 }
 
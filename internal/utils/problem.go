@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const problemTypePrefix = "urn:ledger:problem:"
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable name for the problem that clients can branch on; Type
+// is the same name as a URI.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+func ProblemResponse(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	WriteProblem(w, r, &Problem{Status: status, Code: code, Detail: detail})
+}
+
+// WriteProblem fills in the type, title and instance of p that are left
+// empty and writes it as application/problem+json.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Type == "" {
+		p.Type = problemTypePrefix + p.Code
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}